@@ -7,28 +7,29 @@ package cfa
 
 import (
 	"fmt"
-	"io/ioutil"
 	"log"
 	"os"
-	"path/filepath"
 	"strconv"
 	"strings"
 
 	"github.com/graphism/exp/cfg"
 	"github.com/graphism/exp/flow"
+	"github.com/graphism/exp/logic"
 	"github.com/mewkiz/pkg/term"
-	"github.com/pkg/errors"
 	"gonum.org/v1/gonum/graph"
-	"gonum.org/v1/gonum/graph/encoding/dot"
 	gonumflow "gonum.org/v1/gonum/graph/flow"
 )
 
 // dbg logs debug messages to standard error, with the prefix "interval:".
 var dbg = log.New(os.Stderr, term.RedBold("interval:")+" ", 0)
 
+// Structure marks the n-way conditionals, loops and 2-way conditionals of g,
+// recording the results in the SwitchHead, SwitchFollow, LoopType, LoopHead,
+// Latch, LoopFollow and IfFollow fields of its nodes.
 func Structure(g *cfg.Graph) {
 	cfg.InitDFSOrder(g)
-	//structLoops(g)
+	structNWay(g)
+	structLoops(g)
 	struct2Way(g)
 }
 
@@ -48,63 +49,56 @@ func DerivedGraphSeq(src *cfg.Graph) []*cfg.Graph {
 	// The first order graph, G^1, is G.
 	G := src
 	G.SetDOTID("G1")
-	createGraph(G)
 	Gs = append(Gs, G)
 	intNum := 1
 	for i := 2; G.Nodes().Len() > 1; i++ {
 		Is := flow.Intervals(G, G.Entry())
+		// G^n is an irreducible limit graph if collapsing its intervals leaves it
+		// unchanged.
+		if flow.IsLimitGraph(G, Is) {
+			break
+		}
+		// The derived graph is collapsed in place, from a clone of G^(n-1).
+		G = G.Clone()
 		for _, I := range Is {
-			// Collapse interval into a single node.
-			newName := fmt.Sprintf("I%d", intNum)
-			delNodes := make(map[string]bool)
-			Inodes := I.Nodes()
-			for Inodes.Next() {
-				n := Inodes.Node()
-				nn := node(n)
-				nn.Attrs["fillcolor"] = "red"
-				nn.Attrs["style"] = "filled"
-				delNodes[nn.DOTID()] = true
-			}
-			// Dump pre-merge.
-			// Store graph DOTID before dump.
-			nameBak := G.DOTID()
-			G.SetDOTID(nameBak + "_a")
-			createGraph(G)
-			for Inodes.Reset(); Inodes.Next(); {
-				n := Inodes.Node()
-				nn := node(n)
-				delete(nn.Attrs, "fillcolor")
-				delete(nn.Attrs, "style")
-			}
-			G.SetDOTID(nameBak)
-
 			// The second order graph, G^2, is derived from G^1 by collapsing each
 			// interval in G^1 into a node.
-
-			G = cfg.Merge(G, delNodes, newName)
-			n, ok := G.NodeWithName(newName)
-			if !ok {
-				panic(fmt.Errorf("unable to locate new node %q after merge", newName))
+			newName := fmt.Sprintf("I%d", intNum)
+			delNodes := make(map[string]bool)
+			for it := I.Nodes(); it.Next(); {
+				name := node(it.Node()).DOTID()
+				if _, ok := G.NodeWithName(name); !ok {
+					panic(fmt.Errorf("unable to locate interval node %q", name))
+				}
+				delNodes[name] = true
 			}
-			n.Attrs["fillcolor"] = "red"
-			n.Attrs["style"] = "filled"
-			name := fmt.Sprintf("G%d_b_%d", i-1, intNum)
-			G.SetDOTID(name)
-			createGraph(G)
-			delete(n.Attrs, "fillcolor")
-			delete(n.Attrs, "style")
+			G.Collapse(delNodes, newName)
 			intNum++
 		}
 		name := fmt.Sprintf("G%d", i)
 		G.SetDOTID(name)
-		createGraph(G)
 		Gs = append(Gs, G)
 	}
 	return Gs
 }
 
 // structLoops marks all nodes of G belonging to loops.
+//
+// Loops are identified in the intervals of each graph of the derived sequence
+// of G, and recorded on the nodes of G they represent.
 func structLoops(G *cfg.Graph) {
+	// DerivedGraphSeq renames the first order graph; restore its DOT ID once
+	// done.
+	id := G.DOTID()
+	defer G.SetDOTID(id)
+	// orig maps from original node to the node of G representing it.
+	orig := make(map[*cfg.Node]*cfg.Node)
+	for _, n := range graph.NodesOf(G.Nodes()) {
+		n := node(n)
+		for _, o := range n.Origins() {
+			orig[o] = n
+		}
+	}
 	Gs := DerivedGraphSeq(G)
 	for _, Gi := range Gs {
 		cfg.InitDFSOrder(Gi)
@@ -115,17 +109,62 @@ func structLoops(G *cfg.Graph) {
 			if !ok {
 				continue
 			}
-			dbg.Println("latch:", latch)
-			head := node(Ii.Head)
-			head.Latch = latch
+			// Locate the header and latch nodes of the loop in G.
+			head := headOf(node(Ii.Head), orig)
+			if head.Latch != nil {
+				// Already the header node of an inner loop.
+				continue
+			}
+			l, ok := latchOf(G, latch, head, orig)
+			if !ok {
+				panic(fmt.Errorf("unable to locate back edge to %q from %q", head.DOTID(), latch.DOTID()))
+			}
+			dbg.Println("latch:", l)
+			head.Latch = l
 
 			// TODO: Check latching node is at the same nesting level of case
 			// statements (if any).
 			// Mark nodes belonging to loop and determine type of loop.
-			loop(Ii, latch)
-			latch.IsLatch = true
+			in := make(map[*cfg.Node]bool)
+			for n := range loopNodes(Ii, latch) {
+				for _, o := range node(n).Origins() {
+					in[orig[o]] = true
+				}
+			}
+			loop(G, head, l, loopBody(G, head, l, in))
+			l.IsLatch = true
+		}
+	}
+}
+
+// headOf returns the node of G representing the header node of the given node
+// of a derived graph; i.e. the first of its nodes in reverse postorder, as the
+// header node of an interval dominates all nodes of the interval.
+func headOf(n *cfg.Node, orig map[*cfg.Node]*cfg.Node) *cfg.Node {
+	var head *cfg.Node
+	for _, o := range n.Origins() {
+		if m := orig[o]; head == nil || m.RevPost < head.RevPost {
+			head = m
+		}
+	}
+	return head
+}
+
+// latchOf returns the node of G representing the given latch node of a derived
+// graph; i.e. the last of its nodes in reverse postorder with a back edge to the
+// header node of G.
+func latchOf(G *cfg.Graph, latch, head *cfg.Node, orig map[*cfg.Node]*cfg.Node) (*cfg.Node, bool) {
+	var l *cfg.Node
+	for _, o := range latch.Origins() {
+		m := orig[o]
+		if !G.HasEdgeFromTo(m.ID(), head.ID()) || !isBackEdge(m, head) && m != head {
+			continue
+		}
+		if l == nil || m.RevPost > l.RevPost {
+			l = m
 		}
 	}
+	return l, l != nil
 }
 
 // findLatch returns the latching node of I(h), the node with the greatest
@@ -156,21 +195,35 @@ func findLatch(I *flow.Interval) (*cfg.Node, bool) {
 // first during depth first search traversal (i.e. has a smaller Pre number), or
 // head == pred, then it is a back edge.
 func isBackEdge(pred, head *cfg.Node) bool {
-	return head.Pre < pred.Pre
+	return head == pred || head.Pre < pred.Pre
 }
 
-// loop marks the nodes belonging to the loop determined by (latch, head), and
-// determines the loop type.
-func loop(I *flow.Interval, latch *cfg.Node) {
+// loopNodes returns the nodes of the interval I belonging to the loop determined
+// by (latch, head).
+func loopNodes(I *flow.Interval, latch *cfg.Node) map[graph.Node]bool {
 	head := node(I.Head)
-	head.LoopHead = head
 	// nodes belonging to loop.
 	nodes := make(map[graph.Node]bool)
 	nodes[head] = true
 	// TODO: Consider moving idom computation Structure, and perform on G rather
 	// than I.
 	domtree := gonumflow.Dominators(head, I)
-	// Mark nodes in loop headed by head.
+	// Nodes from which the latch node is reachable without passing through the
+	// header node.
+	reach := make(map[graph.Node]bool)
+	var walk func(n graph.Node)
+	walk = func(n graph.Node) {
+		reach[n] = true
+		preds := I.To(n.ID())
+		for preds.Next() {
+			pred := preds.Node()
+			if pred == head || reach[pred] || I.Node(pred.ID()) == nil {
+				continue
+			}
+			walk(pred)
+		}
+	}
+	walk(latch)
 	for _, n := range cfg.SortByRevPost(graph.NodesOf(I.Nodes())) {
 		nn := node(n)
 		if nn.RevPost <= head.RevPost {
@@ -182,33 +235,75 @@ func loop(I *flow.Interval, latch *cfg.Node) {
 		if idom := domtree.DominatorOf(n.ID()); !nodes[idom] {
 			continue
 		}
+		if !reach[n] {
+			continue
+		}
 		nodes[nn] = true
+	}
+	nodes[latch] = true
+	return nodes
+}
+
+// loopBody returns the nodes of G belonging to the loop determined by (latch,
+// head); i.e. the nodes of in from which the latch node is reachable without
+// passing through the header node. The nodes of a derived graph represent
+// entire intervals of G, which may contain nodes not part of the loop.
+func loopBody(G *cfg.Graph, head, latch *cfg.Node, in map[*cfg.Node]bool) map[*cfg.Node]bool {
+	nodes := make(map[*cfg.Node]bool)
+	nodes[head] = true
+	var walk func(n *cfg.Node)
+	walk = func(n *cfg.Node) {
+		nodes[n] = true
+		preds := G.To(n.ID())
+		for preds.Next() {
+			pred := node(preds.Node())
+			if nodes[pred] || !in[pred] {
+				continue
+			}
+			walk(pred)
+		}
+	}
+	walk(latch)
+	return nodes
+}
+
+// loop marks the given nodes of G as belonging to the loop determined by
+// (latch, head), and determines the loop type.
+func loop(G *cfg.Graph, head, latch *cfg.Node, nodes map[*cfg.Node]bool) {
+	head.LoopHead = head
+	// Mark nodes in loop headed by head.
+	for n := range nodes {
 		// Set loop header if not yet part of another loop.
-		if nn.LoopHead == nil {
-			nn.LoopHead = head
+		if n.LoopHead == nil {
+			n.LoopHead = head
 		}
 	}
-	latch.LoopHead = head
-	nodes[latch] = true
 
 	// Determine loop type.
 	switch {
 	// 2-way latch node.
-	case I.From(latch.ID()).Len() == 2:
+	case G.From(latch.ID()).Len() == 2:
 		switch {
-		// 1-way header node.
-		case I.From(head.ID()).Len() == 1:
+		// 1-way header node, or self-loop.
+		case G.From(head.ID()).Len() == 1, latch == head:
 			head.LoopType = cfg.LoopTypePostTest
 		// 2-way header node.
 		default:
-			// use heuristic to determine best type of loop.
-			panic("loop type detection heuristic not yet implemented for 2-way header node, 2-way latch node loops")
+			// Use heuristic to determine best type of loop; a header node with
+			// both successors inside the loop cannot be the loop condition.
+			succs := graph.NodesOf(G.From(head.ID()))
+			if nodes[node(succs[0])] && nodes[node(succs[1])] {
+				head.LoopType = cfg.LoopTypePostTest
+			} else {
+				head.LoopType = cfg.LoopTypePreTest
+			}
 		}
 	// 1-way latch node.
 	default:
+		succs := graph.NodesOf(G.From(head.ID()))
 		switch {
-		// 2-way header node.
-		case I.From(head.ID()).Len() == 2:
+		// 2-way header node, with a successor outside of the loop.
+		case len(succs) == 2 && !(nodes[node(succs[0])] && nodes[node(succs[1])]):
 			head.LoopType = cfg.LoopTypePreTest
 		// 1-way header node.
 		default:
@@ -222,24 +317,113 @@ func loop(I *flow.Interval, latch *cfg.Node) {
 	switch head.LoopType {
 	case cfg.LoopTypePreTest:
 		// Follow node is the successor of the header node not part of loop nodes.
-		succs := graph.NodesOf(I.From(head.ID()))
-		if nodes[succs[0]] {
+		succs := graph.NodesOf(G.From(head.ID()))
+		if nodes[node(succs[0])] {
 			head.LoopFollow = node(succs[1])
 		} else {
 			head.LoopFollow = node(succs[0])
 		}
 	case cfg.LoopTypePostTest:
 		// Follow node is the successor of the latch node not part of loop nodes.
-		succs := graph.NodesOf(I.From(latch.ID()))
-		if nodes[succs[0]] {
+		succs := graph.NodesOf(G.From(latch.ID()))
+		if nodes[node(succs[0])] {
 			head.LoopFollow = node(succs[1])
 		} else {
 			head.LoopFollow = node(succs[0])
 		}
 	case cfg.LoopTypeEndless:
-		// Determine follow node (if any) by traversing all nodes in the loop.
-		panic("determination of follow node for endless loops not yet implemented")
+		// Determine follow node (if any) by traversing all nodes in the loop;
+		// the follow node is the successor with the smallest reverse postorder
+		// number not part of loop nodes.
+		for n := range nodes {
+			succs := G.From(n.ID())
+			for succs.Next() {
+				succ := node(succs.Node())
+				if nodes[succ] {
+					continue
+				}
+				if head.LoopFollow == nil || succ.RevPost < head.LoopFollow.RevPost {
+					head.LoopFollow = succ
+				}
+			}
+		}
+	}
+}
+
+// structNWay marks all nodes of G belonging to n-way conditionals.
+//
+// Pre: G is a graph numbered in reverse postorder.
+//
+// Post: n-way conditionals are marked in G. the follow node for all n-way
+// conditionals is determined.
+func structNWay(G *cfg.Graph) {
+	domtree := gonumflow.Dominators(G.Entry(), G)
+	// Analyze in descending order, so that inner n-way conditionals are marked
+	// before the outer ones.
+	for _, m := range cfg.SortByPost(graph.NodesOf(G.Nodes())) {
+		if G.From(m.ID()).Len() <= 2 {
+			continue
+		}
+		mm := node(m)
+		mm.SwitchHead = mm
+		if n, ok := findNWayFollow(G, m, domtree); ok {
+			mm.SwitchFollow = n
+		}
+		// Mark nodes belonging to the n-way conditional; i.e. nodes dominated by
+		// the header node and reachable from it without passing through the
+		// follow node.
+		visited := make(map[graph.Node]bool)
+		var walk func(n graph.Node)
+		walk = func(n graph.Node) {
+			visited[n] = true
+			succs := G.From(n.ID())
+			for succs.Next() {
+				succ := succs.Node()
+				ss := node(succ)
+				if visited[succ] || ss == mm.SwitchFollow || !dominates(domtree, m, succ) {
+					continue
+				}
+				// Set switch header if not yet part of another n-way conditional.
+				if ss.SwitchHead == nil {
+					ss.SwitchHead = mm
+				}
+				walk(succ)
+			}
+		}
+		walk(m)
+	}
+}
+
+// findNWayFollow locates the follow node of the n-way conditional; the node
+// immediately dominated by the header node with the greatest number of in-edges,
+// and the greatest reverse postorder number in case of ties.
+func findNWayFollow(G *cfg.Graph, m graph.Node, domtree gonumflow.DominatorTree) (*cfg.Node, bool) {
+	var n *cfg.Node
+	nIn := 0
+	for _, i := range cfg.SortByRevPost(graph.NodesOf(G.Nodes())) {
+		if domtree.DominatorOf(i.ID()) != m {
+			continue
+		}
+		iIn := G.To(i.ID()).Len()
+		if iIn < 2 {
+			continue
+		}
+		if n == nil || iIn > nIn || (iIn == nIn && i.RevPost > n.RevPost) {
+			n = i
+			nIn = iIn
+		}
+	}
+	return n, n != nil
+}
+
+// dominates reports whether m dominates n.
+func dominates(domtree gonumflow.DominatorTree, m, n graph.Node) bool {
+	for d := n; d != nil; d = domtree.DominatorOf(d.ID()) {
+		if d.ID() == m.ID() {
+			return true
+		}
 	}
+	return false
 }
 
 // struct2Way marks all nodes of G belonging to 2-way conditionals.
@@ -264,7 +448,9 @@ func struct2Way(G *cfg.Graph) {
 		if G.From(m.ID()).Len() != 2 {
 			continue
 		}
-		if mm.LoopHead == m {
+		// The conditional of the header node of an endless loop is not the loop
+		// condition.
+		if mm.LoopHead == m && mm.LoopType != cfg.LoopTypeEndless {
 			continue
 		}
 		if mm.IsLatch {
@@ -310,13 +496,19 @@ func find2WayFollow(G *cfg.Graph, m graph.Node, domtree gonumflow.DominatorTree)
 }
 
 // CompoundCond merges the basic blocks of compound conditions into single basic
-// blocks.
+// blocks. Compound conditions of arbitrary length and nesting are merged
+// pairwise until no further merges are possible. The expression tree of each
+// merged condition is recorded in the Cond field of the merged node, and the
+// original 2-way nodes in its CondNodes field. The nodes of g are merged in
+// place, and g is returned.
 func CompoundCond(g *cfg.Graph) *cfg.Graph {
 	change := true
 	for change {
 		change = false
 		// Traverse nodes in postorder, this way, the header node of a compound
-		// condition is analyzed first.
+		// condition is analyzed first. Restart the traversal after each merge,
+		// as the merged nodes are no longer part of g.
+	nodes:
 		for _, n := range cfg.SortByRevPost(graph.NodesOf(g.Nodes())) {
 			if g.From(n.ID()).Len() != 2 {
 				continue
@@ -329,32 +521,36 @@ func CompoundCond(g *cfg.Graph) *cfg.Graph {
 				y := g.TrueTarget(x)
 				e := g.FalseTarget(x)
 				t := g.TrueTarget(y)
-				g = mergeCond(g, x, y, e, t, "CondAND")
+				mergeCond(g, x, y, e, t, "CondAND", logic.NewAnd(NodeCond(x), NodeCond(y)))
 				change = true
+				break nodes
 			case compoundCondOR(g, nn):
 				dbg.Println("OR located at:", nn)
 				x := nn
 				t := g.TrueTarget(x)
 				y := g.FalseTarget(x)
 				e := g.FalseTarget(y)
-				g = mergeCond(g, x, y, e, t, "CondOR")
+				mergeCond(g, x, y, e, t, "CondOR", logic.NewOr(NodeCond(x), NodeCond(y)))
 				change = true
+				break nodes
 			case compoundCondNAND(g, nn):
 				dbg.Println("NAND located at:", nn)
 				x := nn
 				e := g.TrueTarget(x)
 				y := g.FalseTarget(x)
 				t := g.TrueTarget(y)
-				g = mergeCond(g, x, y, e, t, "CondNAND")
+				mergeCond(g, x, y, e, t, "CondNAND", logic.NewAnd(logic.NewNot(NodeCond(x)), NodeCond(y)))
 				change = true
+				break nodes
 			case compoundCondNOR(g, nn):
 				dbg.Println("NOR located at:", nn)
 				x := nn
 				y := g.TrueTarget(x)
 				t := g.FalseTarget(x)
 				e := g.FalseTarget(y)
-				g = mergeCond(g, x, y, e, t, "CondNOR")
+				mergeCond(g, x, y, e, t, "CondNOR", logic.NewOr(logic.NewNot(NodeCond(x)), NodeCond(y)))
 				change = true
+				break nodes
 			}
 		}
 	}
@@ -380,10 +576,7 @@ func compoundCondAND(g *cfg.Graph, x *cfg.Node) bool {
 	if g.To(y.ID()).Len() == 1 && g.From(y.ID()).Len() == 2 {
 		t := g.TrueTarget(y)   // true branch
 		e2 := g.FalseTarget(y) // false branch
-		if e == e2 {
-			return true
-		}
-		_ = t
+		return e == e2 && isCondPair(x, y, e, t)
 	}
 	return false
 }
@@ -407,10 +600,7 @@ func compoundCondOR(g *cfg.Graph, x *cfg.Node) bool {
 	if g.To(y.ID()).Len() == 1 && g.From(y.ID()).Len() == 2 {
 		t2 := g.TrueTarget(y) // true branch
 		e := g.FalseTarget(y) // false branch
-		if t == t2 {
-			return true
-		}
-		_ = e
+		return t == t2 && isCondPair(x, y, e, t)
 	}
 	return false
 }
@@ -434,10 +624,7 @@ func compoundCondNAND(g *cfg.Graph, x *cfg.Node) bool {
 	if g.To(y.ID()).Len() == 1 && g.From(y.ID()).Len() == 2 {
 		t := g.TrueTarget(y)   // true branch
 		e2 := g.FalseTarget(y) // false branch
-		if e == e2 {
-			return true
-		}
-		_ = t
+		return e == e2 && isCondPair(x, y, e, t)
 	}
 	return false
 }
@@ -461,15 +648,13 @@ func compoundCondNOR(g *cfg.Graph, x *cfg.Node) bool {
 	if g.To(y.ID()).Len() == 1 && g.From(y.ID()).Len() == 2 {
 		t2 := g.TrueTarget(y) // true branch
 		e := g.FalseTarget(y) // false branch
-		if t == t2 {
-			return true
-		}
-		_ = e
+		return t == t2 && isCondPair(x, y, e, t)
 	}
 	return false
 }
 
-// mergeCond merges the nodes x and y of the given compound condition.
+// mergeCond merges the nodes x and y of the given compound condition in place,
+// and records the compound condition cond of the merged node.
 //
 // Example merge for x AND y.
 //
@@ -484,53 +669,40 @@ func compoundCondNOR(g *cfg.Graph, x *cfg.Node) bool {
 //       x&&y
 //      ↙    ↘
 //    e        t
-func mergeCond(g *cfg.Graph, x, y, e, t *cfg.Node, name string) *cfg.Graph {
+func mergeCond(g *cfg.Graph, x, y, e, t *cfg.Node, name string, cond logic.Expr) {
 	// Replace x and y node with new (x AND y) node.
 	delNodes := map[string]bool{
 		x.DOTID(): true,
 		y.DOTID(): true,
 	}
 	newName := fmt.Sprintf("%s_%s", unquote(x.DOTID()), name)
-	g = cfg.Merge(g, delNodes, newName)
-	n, ok := g.NodeWithName(newName)
-	if !ok {
-		panic(fmt.Errorf("unable to locate compound condition node %q", newName))
-	}
+	n := g.Collapse(delNodes, newName)
+	n.Cond = cond
+	n.CondNodes = append(condNodes(x), condNodes(y)...)
 	trueEdge := edge(g.Edge(n.ID(), t.ID()))
 	falseEdge := edge(g.Edge(n.ID(), e.ID()))
 	trueEdge.Attrs["label"] = "true"
 	falseEdge.Attrs["label"] = "false"
-	return g
 }
 
-// ### [ Helper functions ] ####################################################
-
-const dir = "_dump_"
-
-func init() {
-	if err := os.RemoveAll(dir); err != nil {
-		log.Fatalf("%+v", errors.WithStack(err))
-	}
-	if err := os.MkdirAll(dir, 0755); err != nil {
-		log.Fatalf("%+v", errors.WithStack(err))
+// condNodes returns the 2-way nodes merged into the given node; or the node
+// itself if not a compound condition.
+func condNodes(n *cfg.Node) []*cfg.Node {
+	if len(n.CondNodes) > 0 {
+		return n.CondNodes
 	}
+	return []*cfg.Node{n}
 }
 
-func createGraph(g *cfg.Graph) {
-	name := g.DOTID()
-	if len(name) == 0 {
-		panic(fmt.Errorf("missing name in graph %v", g))
-	}
-	buf, err := dot.Marshal(g, name, "", "\t")
-	if err != nil {
-		log.Fatalf("%+v", errors.WithStack(err))
-	}
-	path := filepath.Join(dir, name+".dot")
-	if err := ioutil.WriteFile(path, buf, 0644); err != nil {
-		log.Fatalf("%+v", errors.WithStack(err))
-	}
+// isCondPair reports whether the 2-way nodes x and y, with the combined
+// successors e and t, may be merged into a compound condition; i.e. that
+// neither node is targeted by a branch of the compound condition.
+func isCondPair(x, y, e, t *cfg.Node) bool {
+	return x != y && x != e && x != t && y != e && y != t
 }
 
+// ### [ Helper functions ] ####################################################
+
 // node asserts that the given node is a control flow graph node.
 func node(n graph.Node) *cfg.Node {
 	if n, ok := n.(*cfg.Node); ok {
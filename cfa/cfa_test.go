@@ -2,10 +2,14 @@ package cfa
 
 import (
 	"io/ioutil"
+	"reflect"
+	"sort"
 	"strings"
 	"testing"
 
 	"github.com/graphism/exp/cfg"
+	"github.com/graphism/exp/flow"
+	"gonum.org/v1/gonum/graph"
 )
 
 func TestDerivedGraphSeq(t *testing.T) {
@@ -29,6 +33,11 @@ func TestDerivedGraphSeq(t *testing.T) {
 			t.Errorf("%q; unable to parse file; %v", gold.path, err)
 			continue
 		}
+		var names []string
+		for _, n := range graph.NodesOf(in.Nodes()) {
+			names = append(names, n.(*cfg.Node).DOTID())
+		}
+		sort.Strings(names)
 		gs := DerivedGraphSeq(in)
 		if len(gs) != len(gold.want) {
 			t.Errorf("%q: number of derived graphs mismatch; expected %d, got %d", gold.path, len(gold.want), len(gs))
@@ -46,6 +55,247 @@ func TestDerivedGraphSeq(t *testing.T) {
 				t.Errorf("%q; output mismatch; expected `%s`, got `%s`", gold.path, want, got)
 				continue
 			}
+			// The nodes of each derived graph partition the original nodes.
+			var origins []string
+			for _, n := range graph.NodesOf(g.Nodes()) {
+				for _, orig := range n.(*cfg.Node).Origins() {
+					origins = append(origins, orig.DOTID())
+				}
+			}
+			sort.Strings(origins)
+			if !reflect.DeepEqual(origins, names) {
+				t.Errorf("%q; original nodes mismatch of %q; expected %v, got %v", gold.path, g.DOTID(), names, origins)
+			}
+		}
+	}
+}
+
+func TestDerivedGraphSeqDOT(t *testing.T) {
+	golden := []struct {
+		path string
+		want string
+	}{
+		{
+			path: "testdata/sample.dot",
+			want: "testdata/sample.dot.derived.golden",
+		},
+	}
+	for _, gold := range golden {
+		in, err := cfg.ParseFile(gold.path)
+		if err != nil {
+			t.Errorf("%q; unable to parse file; %v", gold.path, err)
+			continue
+		}
+		buf, err := ioutil.ReadFile(gold.want)
+		if err != nil {
+			t.Errorf("%q; unable to read file; %v", gold.path, err)
+			continue
+		}
+		want := strings.TrimSpace(string(buf))
+		got := DerivedGraphSeqDOT(DerivedGraphSeq(in))
+		if got != want {
+			t.Errorf("%q; output mismatch; expected `%s`, got `%s`", gold.path, want, got)
+		}
+	}
+}
+
+func TestStructureDOT(t *testing.T) {
+	golden := []struct {
+		path string
+		want string
+	}{
+		{
+			path: "testdata/sample.dot",
+			want: "testdata/sample.dot.structure.golden",
+		},
+		{
+			path: "testdata/structural.dot",
+			want: "testdata/structural.dot.structure.golden",
+		},
+		// Self-loop nested within a loop.
+		{
+			path: "testdata/self_loop.dot",
+			want: "testdata/self_loop.dot.structure.golden",
+		},
+	}
+	for _, gold := range golden {
+		g, err := cfg.ParseFile(gold.path)
+		if err != nil {
+			t.Errorf("%q; unable to parse file; %v", gold.path, err)
+			continue
+		}
+		buf, err := ioutil.ReadFile(gold.want)
+		if err != nil {
+			t.Errorf("%q; unable to read file; %v", gold.path, err)
+			continue
+		}
+		want := strings.TrimSpace(string(buf))
+		Structure(g)
+		got := StructureDOT(g)
+		if got != want {
+			t.Errorf("%q; output mismatch; expected `%s`, got `%s`", gold.path, want, got)
+		}
+	}
+}
+
+func TestReachingConds(t *testing.T) {
+	golden := []struct {
+		path string
+		want map[string]string
+	}{
+		{
+			path: "testdata/reach.dot",
+			want: map[string]string{
+				"A": "true",
+				"B": "c_A",
+				"C": "!c_A",
+				"D": "c_A || c_C",
+				"S": "c_A || c_C",
+				"E": "c_A && x_S == 1 || c_C && x_S == 1",
+				"F": "c_A && x_S == 2 || c_C && x_S == 2",
+				"G": "c_A && x_S != 1 && x_S != 2 || c_C && x_S != 1 && x_S != 2",
+				"H": "c_A || c_C",
+				"I": "c_A && !c_H || c_C && !c_H",
+				"J": "!c_A && !c_C || c_A && !c_H || c_C && !c_H",
+			},
+		},
+	}
+	for _, gold := range golden {
+		g, err := cfg.ParseFile(gold.path)
+		if err != nil {
+			t.Errorf("%q; unable to parse file; %v", gold.path, err)
+			continue
+		}
+		cfg.InitDFSOrder(g)
+		conds := ReachingConds(Region(g, g), g.Entry())
+		if len(conds) != len(gold.want) {
+			t.Errorf("%q: number of reaching conditions mismatch; expected %d, got %d", gold.path, len(gold.want), len(conds))
+			continue
+		}
+		for n, cond := range conds {
+			name := node(n).DOTID()
+			want := gold.want[name]
+			got := cond.String()
+			if got != want {
+				t.Errorf("%q; reaching condition mismatch of node %q; expected `%s`, got `%s`", gold.path, name, want, got)
+			}
+		}
+	}
+}
+
+func TestCompoundCond(t *testing.T) {
+	golden := []struct {
+		path string
+		// Name of compound condition node.
+		name string
+		// Compound condition.
+		cond string
+		// Names of merged nodes.
+		nodes []string
+	}{
+		{
+			path:  "testdata/compound.dot",
+			name:  "A_CondAND_CondAND_CondOR",
+			cond:  "c_A && c_B && c_C || !c_D && c_E",
+			nodes: []string{"A", "B", "C", "D", "E"},
+		},
+	}
+	for _, gold := range golden {
+		g, err := cfg.ParseFile(gold.path)
+		if err != nil {
+			t.Errorf("%q; unable to parse file; %v", gold.path, err)
+			continue
+		}
+		cfg.InitDFSOrder(g)
+		g = CompoundCond(g)
+		n, ok := g.NodeWithName(gold.name)
+		if !ok {
+			t.Errorf("%q; unable to locate compound condition node %q", gold.path, gold.name)
+			continue
+		}
+		if got := n.Cond.String(); got != gold.cond {
+			t.Errorf("%q; compound condition mismatch; expected `%s`, got `%s`", gold.path, gold.cond, got)
+		}
+		var names []string
+		for _, m := range n.CondNodes {
+			names = append(names, m.DOTID())
+		}
+		if got, want := strings.Join(names, " "), strings.Join(gold.nodes, " "); got != want {
+			t.Errorf("%q; merged nodes mismatch; expected %q, got %q", gold.path, want, got)
+		}
+	}
+}
+
+func TestStructuralAnalysis(t *testing.T) {
+	golden := []struct {
+		path string
+		want string
+	}{
+		{
+			path: "testdata/structural.dot",
+			want: "block(if-then-else(block(A, B), C, D), if-then(E, F), G, self-loop(block(H, I)), case(S, S1, S2, S3), J, while-loop(K, L), M)",
+		},
+		{
+			path: "testdata/sample.dot",
+			want: "block(if-then(B1, if-then-else(B2, B3, B4)), B5, while-loop(B6, block(B12, self-loop(block(B13, B14)), B15)), proper(B7, B8, B9), B10, B11)",
+		},
+		{
+			path: "testdata/reach.dot",
+			want: "block(if-then(natural-loop(A, B, block(case(block(D, S), E, F, G), H), C), I), J)",
+		},
+		{
+			path: "testdata/improper.dot",
+			want: "block(improper(A, B, C), D)",
+		},
+		{
+			path: "testdata/improper_loop.dot",
+			want: "block(improper(A, P, Q, C, D), E)",
+		},
+	}
+	for _, gold := range golden {
+		g, err := cfg.ParseFile(gold.path)
+		if err != nil {
+			t.Errorf("%q; unable to parse file; %v", gold.path, err)
+			continue
+		}
+		tree := StructuralAnalysis(g)
+		if got := tree.String(); got != gold.want {
+			t.Errorf("%q; control tree mismatch; expected `%s`, got `%s`", gold.path, gold.want, got)
+			continue
+		}
+		if got, want := len(tree.Nodes()), g.Nodes().Len(); got != want {
+			t.Errorf("%q; number of basic blocks mismatch; expected %d, got %d", gold.path, want, got)
+		}
+	}
+}
+
+func TestReducibility(t *testing.T) {
+	golden := []struct {
+		path string
+		want bool
+	}{
+		{path: "testdata/sample.dot", want: true},
+		{path: "testdata/structural.dot", want: true},
+		{path: "testdata/reach.dot", want: true},
+		{path: "testdata/self_loop.dot", want: true},
+		{path: "testdata/improper.dot", want: false},
+		{path: "testdata/improper_loop.dot", want: false},
+	}
+	for _, gold := range golden {
+		g, err := cfg.ParseFile(gold.path)
+		if err != nil {
+			t.Errorf("%q; unable to parse file; %v", gold.path, err)
+			continue
+		}
+		// T1/T2 reduction.
+		if got := flow.IsReducible(g, g.Entry()); got != gold.want {
+			t.Errorf("%q; T1/T2 reducibility mismatch; expected %v, got %v", gold.path, gold.want, got)
+		}
+		// Interval method; the limit graph of the derived sequence is trivial on
+		// reducible graphs.
+		gs := DerivedGraphSeq(g)
+		if got := gs[len(gs)-1].Nodes().Len() == 1; got != gold.want {
+			t.Errorf("%q; interval reducibility mismatch; expected %v, got %v", gold.path, gold.want, got)
 		}
 	}
 }
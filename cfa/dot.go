@@ -0,0 +1,262 @@
+package cfa
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/graphism/exp/cfg"
+	"gonum.org/v1/gonum/graph"
+)
+
+// DerivedGraphSeqDOT returns the derived sequence of graphs, G^1 ... G^n, as
+// produced by DerivedGraphSeq, in DOT format.
+//
+// Each graph G^i is drawn as a cluster subgraph, within which the nodes of
+// each interval of G^i are drawn as a nested cluster subgraph, labelled by the
+// name of the node it is collapsed into in G^(i+1). Dashed edges link each
+// collapsed node of G^(i+1) to the member nodes of its interval in G^i.
+func DerivedGraphSeqDOT(Gs []*cfg.Graph) string {
+	buf := &strings.Builder{}
+	buf.WriteString("digraph derived_seq {\n")
+	for i, G := range Gs {
+		prefix := fmt.Sprintf("G%d", i+1)
+		fmt.Fprintf(buf, "\tsubgraph %s {\n", dotID("cluster_"+prefix))
+		fmt.Fprintf(buf, "\t\tlabel=%s;\n", dotID(prefix))
+		nodes := sortByID(graph.NodesOf(G.Nodes()))
+		if i+1 < len(Gs) {
+			// Group the nodes of G^i by interval; i.e. by the node of G^(i+1)
+			// they are collapsed into.
+			next := Gs[i+1]
+			parents := intervalNodes(next)
+			members := make(map[*cfg.Node][]*cfg.Node)
+			for _, n := range nodes {
+				parent := parents[n.Origins()[0].DOTID()]
+				members[parent] = append(members[parent], n)
+			}
+			for _, parent := range sortByID(graph.NodesOf(next.Nodes())) {
+				fmt.Fprintf(buf, "\t\tsubgraph %s {\n", dotID("cluster_"+prefix+"_"+unquote(parent.DOTID())))
+				fmt.Fprintf(buf, "\t\t\tlabel=%s;\n", dotID(unquote(parent.DOTID())))
+				buf.WriteString("\t\t\tstyle=dashed;\n")
+				for _, n := range members[parent] {
+					writeDOTNode(buf, "\t\t\t", prefix, G, n, nil)
+				}
+				buf.WriteString("\t\t}\n")
+			}
+		} else {
+			for _, n := range nodes {
+				writeDOTNode(buf, "\t\t", prefix, G, n, nil)
+			}
+		}
+		for _, from := range nodes {
+			for _, to := range sortByID(graph.NodesOf(G.From(from.ID()))) {
+				e := edge(G.Edge(from.ID(), to.ID()))
+				writeDOTEdge(buf, "\t\t", prefix, from, to, e.Attrs)
+			}
+		}
+		buf.WriteString("\t}\n")
+	}
+	// Link collapsed nodes of G^(i+1) to the nodes of their interval in G^i.
+	for i := 0; i+1 < len(Gs); i++ {
+		G, next := Gs[i], Gs[i+1]
+		parents := intervalNodes(next)
+		for _, n := range sortByID(graph.NodesOf(G.Nodes())) {
+			parent := parents[n.Origins()[0].DOTID()]
+			fmt.Fprintf(buf, "\t%s -> %s [color=gray, constraint=false, style=dashed];\n", nodeDOTID(fmt.Sprintf("G%d", i+2), parent), nodeDOTID(fmt.Sprintf("G%d", i+1), n))
+		}
+	}
+	buf.WriteString("}")
+	return buf.String()
+}
+
+// StructureDOT returns the control flow graph g in DOT format, annotated with
+// the results of Structure.
+//
+// The nodes of each loop are drawn as a cluster subgraph labelled by the loop
+// type, with nested loops drawn as nested clusters. Latch nodes are drawn with
+// a double border, back edges are drawn in blue, and the latching edge of each
+// loop in bold blue. Dashed edges go from each 2-way conditional to its follow
+// node (gray), from each n-way conditional to its follow node (purple), and
+// from each loop header to its follow node (orange).
+//
+// Pre: g has been structured by Structure.
+func StructureDOT(g *cfg.Graph) string {
+	nodes := sortByID(graph.NodesOf(g.Nodes()))
+	// Loop header nodes, sorted in reverse postorder.
+	var heads []*cfg.Node
+	for _, n := range nodes {
+		if isLoopHead(n) {
+			heads = append(heads, n)
+		}
+	}
+	sort.SliceStable(heads, func(i, j int) bool {
+		return heads[i].RevPost < heads[j].RevPost
+	})
+	// Nest each loop within the innermost loop enclosing it; i.e. the loop with
+	// the greatest header spanning its header and latch in reverse postorder.
+	//
+	// parents maps from loop header to the header of its enclosing loop; or nil
+	// if not nested.
+	parents := make(map[*cfg.Node]*cfg.Node)
+	for _, h := range heads {
+		for _, outer := range heads {
+			if outer != h && outer.RevPost <= h.RevPost && h.Latch.RevPost <= outer.Latch.RevPost {
+				parents[h] = outer
+			}
+		}
+	}
+	// members maps from loop header (or nil for the outermost level) to the
+	// nodes and nested loop headers directly contained within the loop.
+	members := make(map[*cfg.Node][]*cfg.Node)
+	for _, n := range nodes {
+		switch {
+		case isLoopHead(n):
+			members[parents[n]] = append(members[parents[n]], n)
+		case n.LoopHead != nil && isLoopHead(n.LoopHead):
+			members[n.LoopHead] = append(members[n.LoopHead], n)
+		default:
+			members[nil] = append(members[nil], n)
+		}
+	}
+	buf := &strings.Builder{}
+	name := unquote(g.DOTID())
+	if len(name) == 0 {
+		name = "structure"
+	}
+	fmt.Fprintf(buf, "digraph %s {\n", dotID(name))
+	var writeNodes func(head *cfg.Node, indent string)
+	writeNodes = func(head *cfg.Node, indent string) {
+		for _, n := range members[head] {
+			if n != head && isLoopHead(n) {
+				fmt.Fprintf(buf, "%ssubgraph %s {\n", indent, dotID("cluster_"+unquote(n.DOTID())))
+				fmt.Fprintf(buf, "%s\tlabel=%s;\n", indent, dotID(n.LoopType.String()))
+				writeNodes(n, indent+"\t")
+				fmt.Fprintf(buf, "%s}\n", indent)
+				continue
+			}
+			var attrs cfg.Attrs
+			if n.IsLatch {
+				attrs = cfg.Attrs{"peripheries": "2"}
+			}
+			writeDOTNode(buf, indent, "", g, n, attrs)
+		}
+	}
+	// Loop headers are members of their own loop.
+	for _, h := range heads {
+		members[h] = append([]*cfg.Node{h}, members[h]...)
+	}
+	writeNodes(nil, "\t")
+	for _, from := range nodes {
+		for _, to := range sortByID(graph.NodesOf(g.From(from.ID()))) {
+			e := edge(g.Edge(from.ID(), to.ID()))
+			attrs := make(cfg.Attrs)
+			for key, val := range e.Attrs {
+				attrs[key] = val
+			}
+			if to.RevPost <= from.RevPost {
+				// Back edge.
+				attrs["color"] = "blue"
+				if isLoopHead(to) && to.Latch == from {
+					attrs["style"] = "bold"
+				}
+			}
+			writeDOTEdge(buf, "\t", "", from, to, attrs)
+		}
+	}
+	// Follow nodes.
+	for _, n := range nodes {
+		if n.IfFollow != nil {
+			writeDOTEdge(buf, "\t", "", n, n.IfFollow, cfg.Attrs{"color": "gray", "constraint": "false", "style": "dashed"})
+		}
+		if n.SwitchHead == n && n.SwitchFollow != nil {
+			writeDOTEdge(buf, "\t", "", n, n.SwitchFollow, cfg.Attrs{"color": "purple", "constraint": "false", "style": "dashed"})
+		}
+		if isLoopHead(n) && n.LoopFollow != nil {
+			writeDOTEdge(buf, "\t", "", n, n.LoopFollow, cfg.Attrs{"color": "orange", "constraint": "false", "style": "dashed"})
+		}
+	}
+	buf.WriteString("}")
+	return buf.String()
+}
+
+// isLoopHead reports whether n is the header node of a loop.
+func isLoopHead(n *cfg.Node) bool {
+	return n.LoopHead == n && n.LoopType != cfg.LoopTypeNone && n.Latch != nil
+}
+
+// intervalNodes returns a mapping from the name of each original node to the
+// node of the derived graph G into which it has been collapsed.
+func intervalNodes(G *cfg.Graph) map[string]*cfg.Node {
+	m := make(map[string]*cfg.Node)
+	for _, n := range graph.NodesOf(G.Nodes()) {
+		n := node(n)
+		for _, orig := range n.Origins() {
+			m[orig.DOTID()] = n
+		}
+	}
+	return m
+}
+
+// writeDOTNode writes the node n of the graph G with the given prefix to buf,
+// with the given additional DOT attributes. The node is labelled by its name,
+// and the entry node is drawn in bold.
+func writeDOTNode(buf *strings.Builder, indent, prefix string, G *cfg.Graph, n *cfg.Node, attrs cfg.Attrs) {
+	a := cfg.Attrs{"label": unquote(n.DOTID())}
+	if n == G.Entry() {
+		a["style"] = "bold"
+	}
+	for key, val := range attrs {
+		a[key] = val
+	}
+	fmt.Fprintf(buf, "%s%s%s;\n", indent, nodeDOTID(prefix, n), dotAttrs(a))
+}
+
+// writeDOTEdge writes the edge from -> to of the graph with the given prefix to
+// buf, with the given DOT attributes.
+func writeDOTEdge(buf *strings.Builder, indent, prefix string, from, to *cfg.Node, attrs cfg.Attrs) {
+	fmt.Fprintf(buf, "%s%s -> %s%s;\n", indent, nodeDOTID(prefix, from), nodeDOTID(prefix, to), dotAttrs(attrs))
+}
+
+// nodeDOTID returns the DOT ID of the node n of the graph with the given
+// prefix; node IDs are prefixed to keep them unique across graphs, unless the
+// prefix is empty.
+func nodeDOTID(prefix string, n *cfg.Node) string {
+	if len(prefix) == 0 {
+		return dotID(unquote(n.DOTID()))
+	}
+	return dotID(prefix + "_" + unquote(n.DOTID()))
+}
+
+// dotAttrs returns the DOT attribute list of the given attributes; or an empty
+// string if no attributes are present.
+func dotAttrs(attrs cfg.Attrs) string {
+	if len(attrs) == 0 {
+		return ""
+	}
+	var as []string
+	for _, attr := range attrs.Attributes() {
+		as = append(as, fmt.Sprintf("%s=%s", attr.Key, dotID(unquote(attr.Value))))
+	}
+	return " [" + strings.Join(as, ", ") + "]"
+}
+
+// dotID returns s as a DOT ID, quoted unless an alphanumeric identifier or an
+// integer.
+func dotID(s string) string {
+	if _, err := strconv.ParseUint(s, 10, 64); err == nil {
+		return s
+	}
+	for i, r := range s {
+		switch {
+		case 'a' <= r && r <= 'z', 'A' <= r && r <= 'Z', r == '_':
+		case '0' <= r && r <= '9' && i > 0:
+		default:
+			return strconv.Quote(s)
+		}
+	}
+	if len(s) == 0 {
+		return `""`
+	}
+	return s
+}
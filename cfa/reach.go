@@ -0,0 +1,188 @@
+package cfa
+
+import (
+	"github.com/graphism/exp/cfg"
+	"github.com/graphism/exp/logic"
+	"gonum.org/v1/gonum/graph"
+)
+
+// CondGraph is a directed graph with conditional edges, such as a region of a
+// control flow graph.
+type CondGraph interface {
+	// Succs returns the successors of n, in the order they are to be visited
+	// by depth first search.
+	Succs(n graph.Node) []graph.Node
+	// EdgeCond returns the condition under which the edge from the node from
+	// to the node to is taken, once from has been executed.
+	EdgeCond(from, to graph.Node) logic.Expr
+}
+
+// ReachingConds returns the reaching conditions of the nodes of g reachable
+// from head; i.e. the condition under which each node is reached from head.
+//
+// Back edges, as located by depth first search from head, are disregarded;
+// thus the reaching conditions of loop bodies are relative to a single
+// iteration of the loop.
+//
+// Conditions are expressed in disjunctive normal form over the atoms of the
+// edge conditions of g.
+func ReachingConds(g CondGraph, head graph.Node) map[graph.Node]logic.Expr {
+	// Order the nodes reachable from head in reverse postorder, and locate back
+	// edges.
+	visited := make(map[graph.Node]bool)
+	onStack := make(map[graph.Node]bool)
+	back := make(map[[2]graph.Node]bool)
+	var order []graph.Node
+	var walk func(n graph.Node)
+	walk = func(n graph.Node) {
+		visited[n] = true
+		onStack[n] = true
+		for _, succ := range g.Succs(n) {
+			switch {
+			case onStack[succ]:
+				back[[2]graph.Node{n, succ}] = true
+			case !visited[succ]:
+				walk(succ)
+			}
+		}
+		onStack[n] = false
+		order = append(order, n)
+	}
+	walk(head)
+	conds := map[graph.Node]logic.Expr{head: logic.True}
+	for i := len(order) - 1; i >= 0; i-- {
+		n := order[i]
+		for _, succ := range g.Succs(n) {
+			if back[[2]graph.Node{n, succ}] {
+				continue
+			}
+			prev, ok := conds[succ]
+			if !ok {
+				prev = logic.False
+			}
+			conds[succ] = logic.DNF(logic.NewOr(prev, logic.NewAnd(conds[n], g.EdgeCond(n, succ))))
+		}
+	}
+	return conds
+}
+
+// Region returns the given region of g as a graph with conditional edges, for
+// use with ReachingConds. Edges from the region to nodes outside of the region
+// are included, so that the reaching conditions of their targets specify the
+// conditions under which the region is exited.
+//
+// The edge conditions of the region are given by EdgeCond, and successors are
+// visited in reverse postorder.
+func Region(g *cfg.Graph, region graph.Graph) CondGraph {
+	return &cfgRegion{g: g, region: region}
+}
+
+// cfgRegion is a region of a control flow graph, with conditional edges.
+type cfgRegion struct {
+	// Control flow graph.
+	g *cfg.Graph
+	// Nodes of the region.
+	region graph.Graph
+}
+
+// Succs returns the successors of n; or none if n is outside of the region.
+func (r *cfgRegion) Succs(n graph.Node) []graph.Node {
+	if r.region.Node(n.ID()) == nil {
+		return nil
+	}
+	var succs []graph.Node
+	for _, succ := range cfg.SortByRevPost(graph.NodesOf(r.g.From(n.ID()))) {
+		succs = append(succs, succ)
+	}
+	return succs
+}
+
+// EdgeCond returns the condition under which the edge from the node from to
+// the node to is taken.
+func (r *cfgRegion) EdgeCond(from, to graph.Node) logic.Expr {
+	return EdgeCond(r.g, node(from), node(to))
+}
+
+// EdgeCond returns the condition under which the edge from the node from to the
+// node to is taken, once from has been executed.
+//
+// The true branch of a 2-way node A is taken under the condition symbol c_A, and
+// the false branch under its negation. The case branches of an n-way node A are
+// taken when the tag symbol x_A equals any of the case values of the edge, and
+// the default branch when it equals none of the case values of the node.
+func EdgeCond(g *cfg.Graph, from, to *cfg.Node) logic.Expr {
+	succs := graph.NodesOf(g.From(from.ID()))
+	switch len(succs) {
+	// Sequence.
+	case 1:
+		return logic.True
+	// 2-way conditional.
+	case 2:
+		c := logic.Sym(CondSym(from))
+		if g.TrueTarget(from) == to {
+			return c
+		}
+		return logic.NewNot(c)
+	// N-way conditional.
+	default:
+		x := logic.Sym(TagSym(from))
+		var cond, def []logic.Expr
+		isDefault := false
+		for _, succ := range succs {
+			e := edge(g.Edge(from.ID(), succ.ID()))
+			values, isDef, err := e.Cases()
+			if err != nil {
+				panic(err)
+			}
+			for _, v := range values {
+				l := logic.Eq{X: x, Value: v}
+				if succ == to {
+					cond = append(cond, l)
+				}
+				def = append(def, logic.NewNot(l))
+			}
+			if succ == to {
+				isDefault = isDef
+			}
+		}
+		if isDefault {
+			cond = append(cond, logic.NewAnd(def...))
+		}
+		return logic.DNF(logic.NewOr(cond...))
+	}
+}
+
+// CondSym returns the condition symbol of the given 2-way node; c_A for the node
+// A.
+func CondSym(n *cfg.Node) string {
+	return "c_" + unquote(n.DOTID())
+}
+
+// TagSym returns the tag symbol of the given n-way node; x_A for the node A.
+func TagSym(n *cfg.Node) string {
+	return "x_" + unquote(n.DOTID())
+}
+
+// NodeCond returns the condition under which the true branch of the given 2-way
+// node is taken; either the compound condition of the node, or its condition
+// symbol.
+func NodeCond(n *cfg.Node) logic.Expr {
+	if n.Cond != nil {
+		return n.Cond
+	}
+	return logic.Sym(CondSym(n))
+}
+
+// CompoundConds returns the compound conditions of the nodes of g, keyed by the
+// condition symbols of the nodes.
+func CompoundConds(g *cfg.Graph) map[logic.Sym]logic.Expr {
+	conds := make(map[logic.Sym]logic.Expr)
+	nodes := g.Nodes()
+	for nodes.Next() {
+		n := node(nodes.Node())
+		if n.Cond != nil {
+			conds[logic.Sym(CondSym(n))] = n.Cond
+		}
+	}
+	return conds
+}
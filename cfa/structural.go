@@ -0,0 +1,626 @@
+// ref: Sharir, Micha. "Structural analysis: a new approach to flow analysis in
+// optimizing compilers." Computer Languages 5.3-4 (1980): 141-153.
+//
+// ref: Muchnick, Steven S. "Advanced compiler design and implementation."
+// Morgan Kaufmann, 1997. Section 7.7.
+
+package cfa
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/graphism/exp/cfg"
+	"gonum.org/v1/gonum/graph"
+	gonumflow "gonum.org/v1/gonum/graph/flow"
+)
+
+//go:generate stringer -type RegionType -linecomment
+
+// RegionType specifies the type of a region of structural analysis.
+type RegionType uint
+
+// Region types.
+const (
+	RegionBasic       RegionType = iota // basic
+	RegionBlock                         // block
+	RegionIfThen                        // if-then
+	RegionIfThenElse                    // if-then-else
+	RegionCase                          // case
+	RegionProper                        // proper
+	RegionSelfLoop                      // self-loop
+	RegionWhileLoop                     // while-loop
+	RegionNaturalLoop                   // natural-loop
+	RegionImproper                      // improper
+)
+
+// ControlNode is a node of the control tree produced by structural analysis;
+// either a basic block of the control flow graph, or a region of nested control
+// nodes.
+type ControlNode struct {
+	// Region type.
+	Type RegionType
+	// Basic block of the control flow graph, or node into which the region was
+	// collapsed.
+	Node *cfg.Node
+	// Control nodes contained immediately within the region. The condition node
+	// of if-then, if-then-else and case regions is followed by the branches of
+	// the region; the true branch before the false branch. The header node of
+	// loops is followed by the loop body. The nodes of remaining regions are in
+	// depth first order.
+	Children []*ControlNode
+}
+
+// Nodes returns the basic blocks contained within the control node, in depth
+// first order of the control tree.
+func (c *ControlNode) Nodes() []*cfg.Node {
+	if c.Type == RegionBasic {
+		return []*cfg.Node{c.Node}
+	}
+	var nodes []*cfg.Node
+	for _, child := range c.Children {
+		nodes = append(nodes, child.Nodes()...)
+	}
+	return nodes
+}
+
+// String returns the string representation of the control node; e.g.
+//
+//    block(A, if-then-else(B, C, D), E)
+func (c *ControlNode) String() string {
+	if c.Type == RegionBasic {
+		return unquote(c.Node.DOTID())
+	}
+	var ss []string
+	for _, child := range c.Children {
+		ss = append(ss, child.String())
+	}
+	return fmt.Sprintf("%v(%s)", c.Type, strings.Join(ss, ", "))
+}
+
+// StructuralAnalysis returns the control tree of g, as produced by structural
+// analysis.
+//
+// Nodes of g are visited in depth first postorder, and the first region schema
+// matching at a node is collapsed into a single node on a copy of g; acyclic
+// schemas (block, if-then, if-then-else, case and proper regions) are tried
+// before cyclic schemas (self-loop, while-loop, natural loop and improper
+// regions). The process is repeated until g has been reduced to a single node.
+// Nodes not reachable from the entry node of g are not part of the control tree.
+func StructuralAnalysis(g *cfg.Graph) *ControlNode {
+	// Collapse regions in place, on a copy of g.
+	dst := cfg.NewGraph()
+	cfg.Copy(dst, g)
+	s := &structural{
+		g:    dst,
+		tree: make(map[*cfg.Node]*ControlNode),
+	}
+	nodes := g.Nodes()
+	for nodes.Next() {
+		n := node(nodes.Node())
+		s.tree[n] = &ControlNode{Type: RegionBasic, Node: n}
+	}
+	for s.reduce() {
+	}
+	return s.tree[node(s.g.Entry())]
+}
+
+// structural tracks the state of structural analysis.
+type structural struct {
+	// Control flow graph being reduced.
+	g *cfg.Graph
+	// Control node of each node of the control flow graph.
+	tree map[*cfg.Node]*ControlNode
+	// Number of collapsed regions.
+	nregions int
+	// Depth first preorder and postorder numbers of the nodes reachable from the
+	// entry node.
+	pre, post map[*cfg.Node]int
+	// Nodes reachable from the entry node, in postorder.
+	order []*cfg.Node
+	// Dominator tree of the control flow graph.
+	domtree gonumflow.DominatorTree
+	// Postdominators of each node; lazily computed.
+	pdoms map[*cfg.Node]map[*cfg.Node]bool
+}
+
+// reduce collapses one region of the control flow graph, and reports whether a
+// region was collapsed.
+func (s *structural) reduce() bool {
+	s.dfs()
+	if len(s.order) <= 1 {
+		return false
+	}
+	s.domtree = gonumflow.Dominators(s.g.Entry(), s.g)
+	s.pdoms = nil
+	for _, n := range s.order {
+		if typ, nodes, ok := s.acyclicRegion(n); ok {
+			s.collapse(typ, nodes)
+			return true
+		}
+		if typ, nodes, ok := s.cyclicRegion(n); ok {
+			s.collapse(typ, nodes)
+			return true
+		}
+	}
+	panic(fmt.Errorf("unable to locate region in control flow graph %q", s.g.DOTID()))
+}
+
+// dfs records the depth first order of the nodes reachable from the entry node.
+func (s *structural) dfs() {
+	s.pre = make(map[*cfg.Node]int)
+	s.post = make(map[*cfg.Node]int)
+	s.order = nil
+	var walk func(n *cfg.Node)
+	walk = func(n *cfg.Node) {
+		s.pre[n] = len(s.pre)
+		for _, succ := range sortByID(graph.NodesOf(s.g.From(n.ID()))) {
+			if _, ok := s.pre[succ]; !ok {
+				walk(succ)
+			}
+		}
+		s.post[n] = len(s.order)
+		s.order = append(s.order, n)
+	}
+	walk(node(s.g.Entry()))
+}
+
+// succs returns the successors of n.
+func (s *structural) succs(n *cfg.Node) []*cfg.Node {
+	return sortByID(graph.NodesOf(s.g.From(n.ID())))
+}
+
+// preds returns the predecessors of n reachable from the entry node.
+func (s *structural) preds(n *cfg.Node) []*cfg.Node {
+	var preds []*cfg.Node
+	for _, pred := range sortByID(graph.NodesOf(s.g.To(n.ID()))) {
+		if _, ok := s.pre[pred]; ok {
+			preds = append(preds, pred)
+		}
+	}
+	return preds
+}
+
+// ancestor reports whether a is an ancestor of b in the depth first spanning
+// tree; a node is its own ancestor.
+func (s *structural) ancestor(a, b *cfg.Node) bool {
+	return s.pre[a] <= s.pre[b] && s.post[b] <= s.post[a]
+}
+
+// --- [ Acyclic regions ] -----------------------------------------------------
+
+// acyclicRegion returns the type and nodes of the acyclic region containing n,
+// and a boolean variable indicating success.
+func (s *structural) acyclicRegion(n *cfg.Node) (RegionType, []*cfg.Node, bool) {
+	entry := node(s.g.Entry())
+	// Block containing n.
+	nodes := []*cfg.Node{n}
+	in := map[*cfg.Node]bool{n: true}
+	for m := n; m != entry; {
+		preds := s.preds(m)
+		if len(preds) != 1 || in[preds[0]] || len(s.succs(preds[0])) != 1 {
+			break
+		}
+		m = preds[0]
+		nodes = append([]*cfg.Node{m}, nodes...)
+		in[m] = true
+	}
+	for m := n; ; {
+		succs := s.succs(m)
+		if len(succs) != 1 || in[succs[0]] || succs[0] == entry || len(s.preds(succs[0])) != 1 {
+			break
+		}
+		m = succs[0]
+		nodes = append(nodes, m)
+		in[m] = true
+	}
+	if len(nodes) >= 2 {
+		return RegionBlock, nodes, true
+	}
+	// single reports whether m is only entered from n and has a single
+	// successor.
+	single := func(m *cfg.Node) bool {
+		return m != n && m != entry && len(s.preds(m)) == 1 && len(s.succs(m)) == 1
+	}
+	succs := s.succs(n)
+	switch {
+	case len(succs) == 2:
+		t, f := s.branches(n)
+		switch {
+		case single(t) && single(f) && s.succs(t)[0] == s.succs(f)[0] && s.succs(t)[0] != n:
+			return RegionIfThenElse, []*cfg.Node{n, t, f}, true
+		case single(t) && s.succs(t)[0] == f && f != n:
+			return RegionIfThen, []*cfg.Node{n, t}, true
+		case single(f) && s.succs(f)[0] == t && t != n:
+			return RegionIfThen, []*cfg.Node{n, f}, true
+		}
+	case len(succs) > 2:
+		// Locate the follow node of the case region; either the successor of
+		// each case, or a direct successor of n.
+		var follow *cfg.Node
+		var cases []*cfg.Node
+		valid := true
+		for _, succ := range succs {
+			target := succ
+			if succ != n && succ != entry && len(s.preds(succ)) == 1 && len(s.succs(succ)) <= 1 {
+				cases = append(cases, succ)
+				if len(s.succs(succ)) == 0 {
+					continue
+				}
+				target = s.succs(succ)[0]
+			}
+			if follow != nil && follow != target {
+				valid = false
+			}
+			follow = target
+		}
+		if valid && follow != n && len(cases) > 0 {
+			return RegionCase, append([]*cfg.Node{n}, cases...), true
+		}
+	}
+	if nodes, ok := s.properRegion(n); ok {
+		return RegionProper, nodes, true
+	}
+	return 0, nil, false
+}
+
+// branches returns the true and false branch targets of the 2-way node n,
+// based on edge labels if present, and successor order otherwise.
+func (s *structural) branches(n *cfg.Node) (t, f *cfg.Node) {
+	succs := s.succs(n)
+	t, f = succs[0], succs[1]
+	if edge(s.g.Edge(n.ID(), t.ID())).Attrs["label"] == "false" && edge(s.g.Edge(n.ID(), f.ID())).Attrs["label"] == "true" {
+		return f, t
+	}
+	return t, f
+}
+
+// properRegion returns the nodes of the acyclic single-entry region headed by n
+// which extends to the immediate postdominator of n, and a boolean variable
+// indicating success.
+func (s *structural) properRegion(n *cfg.Node) ([]*cfg.Node, bool) {
+	follow := s.ipdom(n)
+	var nodes []*cfg.Node
+	in := make(map[*cfg.Node]bool)
+	var walk func(m *cfg.Node) bool
+	walk = func(m *cfg.Node) bool {
+		if !s.dominates(n, m) {
+			return false
+		}
+		in[m] = true
+		nodes = append(nodes, m)
+		for _, succ := range s.succs(m) {
+			if succ == follow || in[succ] {
+				continue
+			}
+			if !walk(succ) {
+				return false
+			}
+		}
+		return true
+	}
+	if !walk(n) || len(nodes) < 2 || s.cyclic(in) {
+		return nil, false
+	}
+	return s.sortByPre(nodes), true
+}
+
+// cyclic reports whether the subgraph induced by the given nodes contains a
+// cycle.
+func (s *structural) cyclic(in map[*cfg.Node]bool) bool {
+	const (
+		white = iota
+		grey
+		black
+	)
+	color := make(map[*cfg.Node]int)
+	var walk func(n *cfg.Node) bool
+	walk = func(n *cfg.Node) bool {
+		color[n] = grey
+		for _, succ := range s.succs(n) {
+			if !in[succ] {
+				continue
+			}
+			switch color[succ] {
+			case grey:
+				return true
+			case white:
+				if walk(succ) {
+					return true
+				}
+			}
+		}
+		color[n] = black
+		return false
+	}
+	for n := range in {
+		if color[n] == white && walk(n) {
+			return true
+		}
+	}
+	return false
+}
+
+// --- [ Cyclic regions ] ------------------------------------------------------
+
+// cyclicRegion returns the type and nodes of the cyclic region headed by n, and
+// a boolean variable indicating success.
+func (s *structural) cyclicRegion(n *cfg.Node) (RegionType, []*cfg.Node, bool) {
+	// Locate the nodes reachable from n, from which n is reached through a back
+	// edge without passing through n.
+	reach := map[*cfg.Node]bool{n: true}
+	var forward func(m *cfg.Node)
+	forward = func(m *cfg.Node) {
+		reach[m] = true
+		for _, succ := range s.succs(m) {
+			if !reach[succ] {
+				forward(succ)
+			}
+		}
+	}
+	forward(n)
+	in := map[*cfg.Node]bool{n: true}
+	nodes := []*cfg.Node{n}
+	selfLoop := false
+	var walk func(m *cfg.Node)
+	walk = func(m *cfg.Node) {
+		in[m] = true
+		nodes = append(nodes, m)
+		for _, pred := range s.preds(m) {
+			if reach[pred] && !in[pred] {
+				walk(pred)
+			}
+		}
+	}
+	for _, pred := range s.preds(n) {
+		switch {
+		case pred == n:
+			selfLoop = true
+		case s.ancestor(n, pred) && !in[pred]:
+			walk(pred)
+		}
+	}
+	if len(nodes) == 1 {
+		if selfLoop {
+			return RegionSelfLoop, nodes, true
+		}
+		return 0, nil, false
+	}
+	// Locate additional entries of the cyclic region.
+	var entries []*cfg.Node
+	for _, m := range nodes[1:] {
+		for _, pred := range s.preds(m) {
+			if !in[pred] {
+				entries = append(entries, m)
+				break
+			}
+		}
+	}
+	if len(entries) > 0 {
+		return RegionImproper, s.improperRegion(n, entries, in), true
+	}
+	nodes = s.sortByPre(nodes)
+	if len(nodes) == 2 {
+		m := nodes[1]
+		if len(s.succs(n)) == 2 && len(s.succs(m)) == 1 && len(s.preds(m)) == 1 {
+			return RegionWhileLoop, nodes, true
+		}
+	}
+	return RegionNaturalLoop, nodes, true
+}
+
+// improperRegion returns the nodes of the minimal single-entry region containing
+// the multiple-entry cyclic region headed by n; i.e. the nearest common
+// dominator of the entries of the cyclic region, and the nodes on paths from the
+// dominator to the cyclic region.
+func (s *structural) improperRegion(n *cfg.Node, entries []*cfg.Node, in map[*cfg.Node]bool) []*cfg.Node {
+	ncd := n
+	for _, m := range entries {
+		ncd = s.commonDominator(ncd, m)
+	}
+	// Nodes dominated by ncd from which the cyclic region is reachable without
+	// passing through ncd; located by walking predecessors backwards from the
+	// cyclic region.
+	region := make(map[*cfg.Node]bool)
+	var queue []*cfg.Node
+	for m := range in {
+		region[m] = true
+		queue = append(queue, m)
+	}
+	for len(queue) > 0 {
+		m := queue[0]
+		queue = queue[1:]
+		if m == ncd {
+			continue
+		}
+		for _, pred := range s.preds(m) {
+			if !region[pred] && s.dominates(ncd, pred) {
+				region[pred] = true
+				queue = append(queue, pred)
+			}
+		}
+	}
+	region[ncd] = true
+	var nodes []*cfg.Node
+	for m := range region {
+		nodes = append(nodes, m)
+	}
+	return s.sortByPre(nodes)
+}
+
+// --- [ Dominators ] ----------------------------------------------------------
+
+// dominates reports whether a dominates b.
+func (s *structural) dominates(a, b *cfg.Node) bool {
+	for m := graph.Node(b); m != nil; m = s.domtree.DominatorOf(m.ID()) {
+		if m.ID() == a.ID() {
+			return true
+		}
+	}
+	return false
+}
+
+// commonDominator returns the nearest common dominator of a and b.
+func (s *structural) commonDominator(a, b *cfg.Node) *cfg.Node {
+	for m := graph.Node(a); m != nil; m = s.domtree.DominatorOf(m.ID()) {
+		if s.dominates(node(m), b) {
+			return node(m)
+		}
+	}
+	return node(s.g.Entry())
+}
+
+// ipdom returns the immediate postdominator of n; or nil if n has no strict
+// postdominator.
+func (s *structural) ipdom(n *cfg.Node) *cfg.Node {
+	if s.pdoms == nil {
+		s.pdoms = s.postDominators()
+	}
+	var ipdom *cfg.Node
+	for m := range s.pdoms[n] {
+		if m == n {
+			continue
+		}
+		// The immediate postdominator is postdominated by all other strict
+		// postdominators of n.
+		if ipdom == nil || len(s.pdoms[m]) > len(s.pdoms[ipdom]) {
+			ipdom = m
+		}
+	}
+	return ipdom
+}
+
+// postDominators returns the postdominators of each node reachable from the
+// entry node. Nodes from which no exit node is reachable have no
+// postdominators.
+func (s *structural) postDominators() map[*cfg.Node]map[*cfg.Node]bool {
+	pdoms := make(map[*cfg.Node]map[*cfg.Node]bool)
+	for _, n := range s.order {
+		if len(s.succs(n)) == 0 {
+			pdoms[n] = map[*cfg.Node]bool{n: true}
+		}
+	}
+	for changed := true; changed; {
+		changed = false
+		for _, n := range s.order {
+			if len(s.succs(n)) == 0 {
+				continue
+			}
+			// Intersect the postdominators of the successors from which an exit
+			// node is reachable.
+			var pdom map[*cfg.Node]bool
+			for _, succ := range s.succs(n) {
+				p, ok := pdoms[succ]
+				if !ok {
+					continue
+				}
+				if pdom == nil {
+					pdom = make(map[*cfg.Node]bool)
+					for m := range p {
+						pdom[m] = true
+					}
+					continue
+				}
+				for m := range pdom {
+					if !p[m] {
+						delete(pdom, m)
+					}
+				}
+			}
+			if pdom == nil {
+				continue
+			}
+			pdom[n] = true
+			if prev, ok := pdoms[n]; !ok || len(prev) != len(pdom) {
+				pdoms[n] = pdom
+				changed = true
+			}
+		}
+	}
+	return pdoms
+}
+
+// --- [ Region collapse ] -----------------------------------------------------
+
+// collapse collapses the given nodes into a single node of the given region
+// type.
+func (s *structural) collapse(typ RegionType, nodes []*cfg.Node) {
+	in := make(map[*cfg.Node]bool)
+	delNodes := make(map[string]bool)
+	var children []*ControlNode
+	for _, n := range nodes {
+		in[n] = true
+		delNodes[n.DOTID()] = true
+		// Flatten nested blocks.
+		if child := s.tree[n]; typ == RegionBlock && child.Type == RegionBlock {
+			children = append(children, child.Children...)
+		} else {
+			children = append(children, child)
+		}
+	}
+	// Edges from within an acyclic region to its entry are retained as a
+	// self-loop of the collapsed node.
+	selfLoop := false
+	if typ < RegionSelfLoop {
+		for _, n := range nodes {
+			if s.g.HasEdgeFromTo(n.ID(), nodes[0].ID()) {
+				selfLoop = true
+			}
+		}
+	}
+	// Record the attributes of edges leaving the region, unless ambiguous.
+	exits := make(map[*cfg.Node]cfg.Attrs)
+	for _, n := range nodes {
+		for _, succ := range s.succs(n) {
+			if in[succ] {
+				continue
+			}
+			if _, ok := exits[succ]; ok {
+				exits[succ] = nil
+				continue
+			}
+			exits[succ] = edge(s.g.Edge(n.ID(), succ.ID())).Attrs
+		}
+	}
+	var name string
+	for {
+		s.nregions++
+		name = fmt.Sprintf("R%d", s.nregions)
+		if _, ok := s.g.NodeWithName(name); !ok {
+			break
+		}
+	}
+	n := s.g.Collapse(delNodes, name)
+	if selfLoop {
+		s.g.SetEdge(s.g.NewEdge(n, n))
+	}
+	for succ, attrs := range exits {
+		e := edge(s.g.Edge(n.ID(), succ.ID()))
+		for key, val := range attrs {
+			e.Attrs[key] = val
+		}
+	}
+	s.tree[n] = &ControlNode{Type: typ, Node: n, Children: children}
+	dbg.Println("region located:", s.tree[n])
+}
+
+// sortByPre sorts the given nodes by depth first preorder.
+func (s *structural) sortByPre(nodes []*cfg.Node) []*cfg.Node {
+	sort.Slice(nodes, func(i, j int) bool {
+		return s.pre[nodes[i]] < s.pre[nodes[j]]
+	})
+	return nodes
+}
+
+// sortByID sorts the given list of nodes by node ID.
+func sortByID(ns []graph.Node) []*cfg.Node {
+	sort.Slice(ns, func(i, j int) bool {
+		return ns[i].ID() < ns[j].ID()
+	})
+	var nodes []*cfg.Node
+	for _, n := range ns {
+		nodes = append(nodes, node(n))
+	}
+	return nodes
+}
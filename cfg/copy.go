@@ -1,8 +1,15 @@
 package cfg
 
+import (
+	"gonum.org/v1/gonum/graph"
+)
+
 // Copy copies nodes and edges as directed edges from the source to the
 // destination without first clearing the destination. Copy will panic if a node
 // ID in the source graph matches a node ID in the destination.
+//
+// The destination shares nodes and edges with the source; changes to the nodes
+// of one graph are visible in the other. Use DeepCopy to copy nodes and edges.
 func Copy(dst, src *Graph) {
 	dst.id = src.id
 	nodes := src.Nodes()
@@ -19,4 +26,68 @@ func Copy(dst, src *Graph) {
 		}
 	}
 	dst.initNodes()
+	if src.nextID > dst.nextID {
+		dst.nextID = src.nextID
+	}
+}
+
+// DeepCopy copies nodes and edges as directed edges from the source to the
+// destination without first clearing the destination, and returns a mapping
+// from source nodes to destination nodes. Unlike Copy, the destination shares no
+// nodes or edges with the source. Node IDs and names are preserved, and
+// references between nodes of the source (e.g. LoopHead and IfFollow) refer to
+// the corresponding nodes of the destination. The original nodes of each copied
+// node, as recorded by Origins, are kept. DeepCopy will panic if a node ID in
+// the source graph matches a node ID in the destination.
+func DeepCopy(dst, src *Graph) map[*Node]*Node {
+	dst.id = src.id
+	m := make(map[*Node]*Node)
+	nodes := src.Nodes()
+	for nodes.Next() {
+		n := node(nodes.Node())
+		dup := &Node{}
+		*dup = *n
+		dup.Attrs = copyAttrs(n.Attrs)
+		dup.origins = n.Origins()
+		m[n] = dup
+	}
+	// remap returns the destination node corresponding to n if present, and n
+	// otherwise.
+	remap := func(n *Node) *Node {
+		if dup, ok := m[n]; ok {
+			return dup
+		}
+		return n
+	}
+	for _, n := range sortByDOTID(graph.NodesOf(src.Nodes())) {
+		dup := m[node(n)]
+		dup.LoopHead = remap(dup.LoopHead)
+		dup.Latch = remap(dup.Latch)
+		dup.LoopFollow = remap(dup.LoopFollow)
+		dup.IfFollow = remap(dup.IfFollow)
+		dup.SwitchHead = remap(dup.SwitchHead)
+		dup.SwitchFollow = remap(dup.SwitchFollow)
+		dst.AddNode(dup)
+	}
+	for nodes.Reset(); nodes.Next(); {
+		u := node(nodes.Node())
+		vnodes := src.From(u.ID())
+		for vnodes.Next() {
+			v := node(vnodes.Node())
+			e := edge(dst.NewEdge(m[u], m[v]))
+			e.Attrs = copyAttrs(edge(src.Edge(u.ID(), v.ID())).Attrs)
+			dst.SetEdge(e)
+		}
+	}
+	if src.nextID > dst.nextID {
+		dst.nextID = src.nextID
+	}
+	return m
+}
+
+// Clone returns a deep copy of g, which shares no nodes or edges with g.
+func (g *Graph) Clone() *Graph {
+	dst := NewGraph()
+	DeepCopy(dst, g)
+	return dst
 }
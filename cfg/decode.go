@@ -4,11 +4,31 @@ import (
 	"fmt"
 	"io"
 	"io/ioutil"
+	"strings"
 
 	"github.com/pkg/errors"
+	"gonum.org/v1/gonum/graph"
 	"gonum.org/v1/gonum/graph/encoding/dot"
+	dotparser "gonum.org/v1/gonum/graph/formats/dot"
+	"gonum.org/v1/gonum/graph/formats/dot/ast"
 )
 
+// ParseOptions specifies how the entry node of control flow graphs is
+// determined when parsing Graphviz DOT files.
+//
+// The entry node is the node with the name of Entry if specified. Otherwise,
+// the node labelled "entry" is used if present; followed by the unique node
+// without predecessors if NoPredsEntry is set, and the node named "0" if not
+// set. As the DOT label of the entry node is reserved for "entry", parsing fails
+// if the entry node has any other label.
+type ParseOptions struct {
+	// Name of the entry node; or empty to detect the entry node.
+	Entry string
+	// NoPredsEntry specifies whether to use the unique node without
+	// predecessors as the entry node, if no node is labelled "entry".
+	NoPredsEntry bool
+}
+
 // Parse parses the given Graphviz DOT file into a control flow graph, reading
 // from r.
 func Parse(r io.Reader) (*Graph, error) {
@@ -32,35 +52,154 @@ func ParseFile(path string) (*Graph, error) {
 // ParseBytes parses the given Graphviz DOT file into a control flow graph,
 // reading from b.
 func ParseBytes(b []byte) (*Graph, error) {
+	return ParseBytesWithOptions(b, nil)
+}
+
+// ParseString parses the given Graphviz DOT file into a control flow graph,
+// reading from s.
+func ParseString(s string) (*Graph, error) {
+	return ParseBytes([]byte(s))
+}
+
+// ParseBytesWithOptions parses the given Graphviz DOT file into a control flow
+// graph, reading from b. The entry node is determined by the given options; or
+// the default options if nil. The DOT file must contain exactly one graph.
+func ParseBytesWithOptions(b []byte, opts *ParseOptions) (*Graph, error) {
+	gs, err := ParseAllBytes(b, opts)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	if len(gs) != 1 {
+		return nil, errors.Errorf("invalid number of graphs in DOT file; expected 1, got %d", len(gs))
+	}
+	return gs[0], nil
+}
+
+// ParseAll parses the given Graphviz DOT file into one control flow graph per
+// graph of the file (e.g. per function), reading from r. The entry node of each
+// graph is determined by the given options; or the default options if nil.
+func ParseAll(r io.Reader, opts *ParseOptions) ([]*Graph, error) {
+	buf, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	return ParseAllBytes(buf, opts)
+}
+
+// ParseAllFile parses the given Graphviz DOT file into one control flow graph
+// per graph of the file (e.g. per function), reading from path. The entry node
+// of each graph is determined by the given options; or the default options if
+// nil.
+func ParseAllFile(path string, opts *ParseOptions) ([]*Graph, error) {
+	buf, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	return ParseAllBytes(buf, opts)
+}
+
+// ParseAllBytes parses the given Graphviz DOT file into one control flow graph
+// per graph of the file (e.g. per function), reading from b. The entry node of
+// each graph is determined by the given options; or the default options if
+// nil.
+//
+// Subgraphs (e.g. clusters) are flattened; their nodes and edges are added to
+// the enclosing control flow graph.
+func ParseAllBytes(b []byte, opts *ParseOptions) ([]*Graph, error) {
+	if opts == nil {
+		opts = &ParseOptions{}
+	}
+	file, err := dotparser.ParseBytes(b)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	var gs []*Graph
+	for i, src := range file.Graphs {
+		g, err := parseGraph(src, opts)
+		if err != nil {
+			name := src.ID
+			if len(name) == 0 {
+				name = fmt.Sprintf("#%d", i+1)
+			}
+			return nil, errors.Wrapf(err, "unable to parse graph %s", name)
+		}
+		gs = append(gs, g)
+	}
+	return gs, nil
+}
+
+// parseGraph parses the given Graphviz DOT graph into a control flow graph.
+func parseGraph(src *ast.Graph, opts *ParseOptions) (*Graph, error) {
+	if !src.Directed {
+		return nil, errors.New("support for undirected graphs not yet implemented")
+	}
 	g := NewGraph()
-	if err := dot.Unmarshal(b, g); err != nil {
+	if err := dot.Unmarshal([]byte(src.String()), g); err != nil {
 		return nil, errors.WithStack(err)
 	}
 	// Initialize mapping between node names and graph nodes.
 	g.initNodes()
-	nodes := g.Nodes()
-	for nodes.Next() {
-		n := nodes.Node()
-		nn := node(n)
-		if nn.entry {
-			if g.entry != nil && nn != g.entry {
-				panic(fmt.Errorf("entry node already set in graph; prev entry node %#v, new entry node %#v", g.entry, nn))
-			}
-			g.entry = nn
-		}
+	entry, err := findEntry(g, opts)
+	if err != nil {
+		return nil, errors.WithStack(err)
 	}
-	if g.entry == nil {
-		n, ok := g.NodeWithName(`"0"`)
-		if !ok {
-			panic(`unable to locate entry note or node with name "0"`)
+	if entry.entry {
+		g.entry = entry
+	} else {
+		// The DOT label of entry nodes is reserved for the "entry" label.
+		if label, ok := entry.Attrs["label"]; ok {
+			return nil, errors.Errorf("invalid DOT label of entry node %q; expected \"entry\", got %q", entry.name, label)
 		}
-		g.SetEntry(n)
+		g.SetEntry(entry)
 	}
 	return g, nil
 }
 
-// ParseString parses the given Graphviz DOT file into a control flow graph,
-// reading from s.
-func ParseString(s string) (*Graph, error) {
-	return ParseBytes([]byte(s))
+// findEntry returns the entry node of g, as determined by the given options.
+func findEntry(g *Graph, opts *ParseOptions) (*Node, error) {
+	var labelled []*Node
+	for _, n := range sortByDOTID(graph.NodesOf(g.Nodes())) {
+		if nn := node(n); nn.entry {
+			labelled = append(labelled, nn)
+		}
+	}
+	if len(labelled) > 1 {
+		return nil, errors.Errorf("ambiguous entry node; %d nodes labelled \"entry\" (%s)", len(labelled), strings.Join(namesOf(labelled), ", "))
+	}
+	if len(opts.Entry) > 0 {
+		n, ok := g.NodeWithName(opts.Entry)
+		if !ok {
+			return nil, errors.Errorf("unable to locate entry node %q", opts.Entry)
+		}
+		if len(labelled) == 1 && labelled[0] != n {
+			return nil, errors.Errorf("ambiguous entry node; entry node %q specified, but node %q labelled \"entry\"", opts.Entry, labelled[0].name)
+		}
+		return n, nil
+	}
+	if len(labelled) == 1 {
+		return labelled[0], nil
+	}
+	if opts.NoPredsEntry {
+		var roots []*Node
+		for _, n := range sortByDOTID(graph.NodesOf(g.Nodes())) {
+			if g.To(n.ID()).Len() == 0 {
+				roots = append(roots, node(n))
+			}
+		}
+		switch len(roots) {
+		case 0:
+			return nil, errors.New("unable to locate entry node; no node labelled \"entry\" and no node without predecessors")
+		case 1:
+			return roots[0], nil
+		default:
+			return nil, errors.Errorf("ambiguous entry node; no node labelled \"entry\" and %d nodes without predecessors (%s)", len(roots), strings.Join(namesOf(roots), ", "))
+		}
+	}
+	// Fall back to the node named "0".
+	for _, name := range []string{`"0"`, "0"} {
+		if n, ok := g.NodeWithName(name); ok {
+			return n, nil
+		}
+	}
+	return nil, errors.New(`unable to locate entry node; no node labelled "entry" and no node named "0"`)
 }
@@ -0,0 +1,195 @@
+package cfg
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+
+	"github.com/pkg/errors"
+)
+
+// disasmFunc is a function of a basic block listing in JSON format.
+type disasmFunc struct {
+	// Function name.
+	Name string `json:"name"`
+	// Address of the entry basic block; or nil to use the first basic block.
+	Entry *disasmAddr `json:"entry"`
+	// Basic blocks of the function.
+	Blocks []*disasmBlock `json:"blocks"`
+}
+
+// disasmBlock is a basic block of a basic block listing in JSON format.
+type disasmBlock struct {
+	// Address of the basic block.
+	Addr disasmAddr `json:"addr"`
+	// Target of the jump at the end of the basic block; or nil if not present.
+	Jump *disasmAddr `json:"jump"`
+	// Fall-through target of the basic block; or nil if not present.
+	Fail *disasmAddr `json:"fail"`
+	// Cases of an n-way conditional at the end of the basic block.
+	Cases []*disasmCase `json:"cases"`
+	// Default target of an n-way conditional; or nil if not present.
+	Default *disasmAddr `json:"default"`
+}
+
+// disasmCase is a case of an n-way conditional.
+type disasmCase struct {
+	// Case value.
+	Value json.RawMessage `json:"value"`
+	// Target of the case.
+	Jump disasmAddr `json:"jump"`
+}
+
+// disasmAddr is an address of a basic block; either a string or a number.
+type disasmAddr string
+
+// UnmarshalJSON decodes the address from a JSON string or number; numbers are
+// formatted in hexadecimal (e.g. "0x401000"). Implements json.Unmarshaler.
+func (addr *disasmAddr) UnmarshalJSON(b []byte) error {
+	var s string
+	if err := json.Unmarshal(b, &s); err == nil {
+		*addr = disasmAddr(s)
+		return nil
+	}
+	var x uint64
+	if err := json.Unmarshal(b, &x); err != nil {
+		return errors.Errorf("invalid address %s; expected JSON string or number", b)
+	}
+	*addr = disasmAddr(fmt.Sprintf("0x%x", x))
+	return nil
+}
+
+// ParseDisasmJSON parses the given basic block listing in JSON format (e.g. as
+// produced by a disassembler) into one control flow graph per function,
+// reading from r.
+//
+// The listing is an array of functions, or a single function:
+//
+//    [
+//       {
+//          "name": "main",
+//          "entry": "0x401000",
+//          "blocks": [
+//             {"addr": "0x401000", "jump": "0x401010", "fail": "0x401008"},
+//             {"addr": "0x401008", "jump": "0x401010"},
+//             {"addr": "0x401010", "cases": [{"value": 1, "jump": "0x401020"}], "default": "0x401030"},
+//             {"addr": "0x401020", "fail": "0x401030"},
+//             {"addr": "0x401030"}
+//          ]
+//       }
+//    ]
+//
+// Addresses are JSON strings or numbers; numbers are formatted in hexadecimal.
+// Nodes are named after the address of their basic block, and the entry node
+// is the basic block at the entry address if present, and the first basic
+// block otherwise. Basic blocks with both a jump and a fall-through target end
+// with a 2-way conditional, which jumps if the condition is true; basic blocks
+// with a single target end with an unconditional jump or fall through, and
+// basic blocks with cases end with an n-way conditional.
+func ParseDisasmJSON(r io.Reader) ([]*Graph, error) {
+	buf, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	var funcs []*disasmFunc
+	if buf = bytes.TrimSpace(buf); bytes.HasPrefix(buf, []byte("{")) {
+		f := &disasmFunc{}
+		if err := json.Unmarshal(buf, f); err != nil {
+			return nil, errors.WithStack(err)
+		}
+		funcs = append(funcs, f)
+	} else if err := json.Unmarshal(buf, &funcs); err != nil {
+		return nil, errors.WithStack(err)
+	}
+	var gs []*Graph
+	for _, f := range funcs {
+		g, err := newDisasmGraph(f)
+		if err != nil {
+			return nil, errors.Wrapf(err, "unable to parse function %q", f.Name)
+		}
+		gs = append(gs, g)
+	}
+	return gs, nil
+}
+
+// newDisasmGraph returns a new control flow graph of the given function of a
+// basic block listing.
+func newDisasmGraph(f *disasmFunc) (*Graph, error) {
+	if len(f.Blocks) == 0 {
+		return nil, errors.New("no basic blocks in function")
+	}
+	g := NewGraph()
+	g.id = f.Name
+	for _, b := range f.Blocks {
+		name := quote(string(b.Addr))
+		if _, ok := g.NodeWithName(name); ok {
+			return nil, errors.Errorf("basic block at address %q already present in function", b.Addr)
+		}
+		nodeWithName(g, name)
+	}
+	// node returns the node of the basic block at the given address.
+	node := func(addr disasmAddr) (*Node, error) {
+		n, ok := g.NodeWithName(quote(string(addr)))
+		if !ok {
+			return nil, errors.Errorf("unable to locate basic block at address %q", addr)
+		}
+		return n, nil
+	}
+	entry := f.Blocks[0].Addr
+	if f.Entry != nil {
+		entry = *f.Entry
+	}
+	n, err := node(entry)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	g.SetEntry(n)
+	for _, b := range f.Blocks {
+		from, err := node(b.Addr)
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+		if len(b.Cases) > 0 || b.Default != nil {
+			for _, c := range b.Cases {
+				to, err := node(c.Jump)
+				if err != nil {
+					return nil, errors.WithStack(err)
+				}
+				value := string(c.Value)
+				var s string
+				if err := json.Unmarshal(c.Value, &s); err == nil {
+					value = s
+				}
+				caseEdge(g, from, to, fmt.Sprintf("case (x=%s)", value))
+			}
+			if b.Default != nil {
+				to, err := node(*b.Default)
+				if err != nil {
+					return nil, errors.WithStack(err)
+				}
+				caseEdge(g, from, to, "default case")
+			}
+			continue
+		}
+		var targets []*Node
+		for _, addr := range []*disasmAddr{b.Jump, b.Fail} {
+			if addr == nil {
+				continue
+			}
+			to, err := node(*addr)
+			if err != nil {
+				return nil, errors.WithStack(err)
+			}
+			targets = append(targets, to)
+		}
+		switch len(targets) {
+		case 1:
+			edgeWithLabel(g, from, targets[0], "")
+		case 2:
+			condEdges(g, from, targets[0], targets[1])
+		}
+	}
+	return g, nil
+}
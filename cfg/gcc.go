@@ -0,0 +1,544 @@
+package cfg
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+	dotparser "gonum.org/v1/gonum/graph/formats/dot"
+	"gonum.org/v1/gonum/graph/formats/dot/ast"
+)
+
+// Basic block numbers of the ENTRY and EXIT blocks of GCC.
+const (
+	gccEntry = 0
+	gccExit  = 1
+)
+
+// gccBlock is a basic block of a GCC control flow graph dump.
+type gccBlock struct {
+	// Basic block number.
+	num int
+	// Labels at the start of the basic block (e.g. "L0" or "D.1234").
+	labels []string
+	// GIMPLE statements of the basic block, one per line.
+	stmts []string
+	// Successors of the basic block; or nil if not recorded by the dump.
+	succs []*gccSucc
+}
+
+// gccSucc is a successor of a basic block in a GCC control flow graph dump.
+type gccSucc struct {
+	// Basic block number of the successor.
+	num int
+	// Edge label; "true", "false" or empty if unknown or unconditional.
+	label string
+}
+
+// ParseGCCGraph parses the given GCC control flow graph dump in Graphviz DOT
+// format (as produced by -fdump-tree-<pass>-graph) into one control flow graph
+// per function, reading from r.
+//
+// Nodes are named after the number of their basic block (e.g. "bb2"); the
+// ENTRY and EXIT blocks are omitted, and the successor of ENTRY is used as the
+// entry node. True and false edges are identified by edge color (forestgreen
+// and darkorange respectively), and otherwise by the GIMPLE statements of the
+// node labels.
+func ParseGCCGraph(r io.Reader) ([]*Graph, error) {
+	buf, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	file, err := dotparser.ParseBytes(buf)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	var gs []*Graph
+	for _, src := range file.Graphs {
+		// Each function is recorded as a cluster subgraph of the graph.
+		var funcs []*ast.Subgraph
+		for _, stmt := range src.Stmts {
+			if sub, ok := stmt.(*ast.Subgraph); ok {
+				funcs = append(funcs, sub)
+			}
+		}
+		if len(funcs) == 0 {
+			funcs = append(funcs, &ast.Subgraph{ID: src.ID, Stmts: src.Stmts})
+		}
+		for _, sub := range funcs {
+			name := strings.TrimPrefix(unquote(sub.ID), "cluster_")
+			g, err := parseGCCGraphFunc(name, sub.Stmts)
+			if err != nil {
+				return nil, errors.Wrapf(err, "unable to parse function %q", name)
+			}
+			gs = append(gs, g)
+		}
+	}
+	return gs, nil
+}
+
+// reGCCNodeID is a regular expression matching DOT node IDs of basic blocks in
+// GCC control flow graph dumps; e.g. "fn_0_basic_block_2".
+var reGCCNodeID = regexp.MustCompile(`basic_block_([0-9]+)$`)
+
+// parseGCCGraphFunc parses the statements of the given function in a GCC
+// control flow graph dump in DOT format.
+func parseGCCGraphFunc(name string, stmts []ast.Stmt) (*Graph, error) {
+	blocks := make(map[int]*gccBlock)
+	// block returns the basic block of the given DOT node ID.
+	block := func(id string) (*gccBlock, error) {
+		m := reGCCNodeID.FindStringSubmatch(unquote(id))
+		if m == nil {
+			return nil, errors.Errorf("invalid basic block node ID %q", id)
+		}
+		num, _ := strconv.Atoi(m[1])
+		b, ok := blocks[num]
+		if !ok {
+			b = &gccBlock{num: num, succs: []*gccSucc{}}
+			blocks[num] = b
+		}
+		return b, nil
+	}
+	entry := -1
+	// Flatten subgraphs (e.g. loop clusters).
+	var walk func(stmts []ast.Stmt) error
+	walk = func(stmts []ast.Stmt) error {
+		for _, stmt := range stmts {
+			switch stmt := stmt.(type) {
+			case *ast.NodeStmt:
+				b, err := block(stmt.Node.ID)
+				if err != nil {
+					return errors.WithStack(err)
+				}
+				for _, attr := range stmt.Attrs {
+					if attr.Key == "label" {
+						b.labels, b.stmts = gccLabelStmts(trimQuotes(attr.Val))
+					}
+				}
+			case *ast.EdgeStmt:
+				from, ok := stmt.From.(*ast.Node)
+				if !ok {
+					return errors.Errorf("support for edge source %T not yet implemented", stmt.From)
+				}
+				to, ok := stmt.To.Vertex.(*ast.Node)
+				if !ok || stmt.To.To != nil {
+					return errors.Errorf("support for edge target %v not yet implemented", stmt.To)
+				}
+				attrs := make(Attrs)
+				for _, attr := range stmt.Attrs {
+					attrs[attr.Key] = unquote(attr.Val)
+				}
+				if strings.Contains(attrs["style"], "invis") {
+					continue
+				}
+				src, err := block(from.ID)
+				if err != nil {
+					return errors.WithStack(err)
+				}
+				dst, err := block(to.ID)
+				if err != nil {
+					return errors.WithStack(err)
+				}
+				switch {
+				case src.num == gccEntry:
+					entry = dst.num
+				case dst.num == gccExit:
+					// Return edge.
+				default:
+					succ := &gccSucc{num: dst.num}
+					switch attrs["color"] {
+					case "forestgreen":
+						succ.label = "true"
+					case "darkorange":
+						succ.label = "false"
+					}
+					src.succs = append(src.succs, succ)
+				}
+			case *ast.Subgraph:
+				if err := walk(stmt.Stmts); err != nil {
+					return errors.WithStack(err)
+				}
+			}
+		}
+		return nil
+	}
+	if err := walk(stmts); err != nil {
+		return nil, errors.WithStack(err)
+	}
+	delete(blocks, gccEntry)
+	delete(blocks, gccExit)
+	var bs []*gccBlock
+	for _, b := range blocks {
+		bs = append(bs, b)
+	}
+	sort.Slice(bs, func(i, j int) bool {
+		return bs[i].num < bs[j].num
+	})
+	if entry == -1 && len(bs) > 0 {
+		// No edge from ENTRY; use the first basic block.
+		entry = bs[0].num
+	}
+	return newGCCGraph(name, bs, entry)
+}
+
+// gccLabelStmts returns the labels and GIMPLE statements of the given DOT
+// record label of a basic block.
+func gccLabelStmts(label string) (labels, stmts []string) {
+	// Split record fields on unescaped separators, and remove escapes of the
+	// remaining special characters.
+	const sep = "\x00"
+	label = strings.NewReplacer("\\\n", "", `\|`, sep).Replace(label)
+	unescape := strings.NewReplacer(sep, "|", `\l`, "\n", `\<`, "<", `\>`, ">", `\ `, " ", `\{`, "{", `\}`, "}", `\"`, `"`, `\\`, `\`)
+	b := &gccBlock{}
+	for _, field := range strings.Split(strings.Trim(label, "{}"), "|") {
+		for _, line := range strings.Split(unescape.Replace(field), "\n") {
+			b.addLine(line)
+		}
+	}
+	return b.labels, b.stmts
+}
+
+// ParseGCCDump parses the given textual GCC control flow graph dump (as
+// produced by -fdump-tree-cfg) into one control flow graph per function,
+// reading from r.
+//
+// Nodes are named after the number of their basic block (e.g. "bb2"), and the
+// first basic block of each function is used as the entry node. Successors are
+// read from the ";; succ:" annotations if present (as produced by the -blocks
+// dump option), and otherwise inferred from the goto, if, switch and return
+// statements of each basic block; basic blocks without such statements fall
+// through to the next basic block.
+func ParseGCCDump(r io.Reader) ([]*Graph, error) {
+	var gs []*Graph
+	var (
+		// Name of the current function; or empty if outside of function.
+		name string
+		// Basic blocks of the current function.
+		blocks []*gccBlock
+		// Current basic block.
+		cur *gccBlock
+		// Successor annotations are being read.
+		inSuccs bool
+	)
+	flush := func() error {
+		if len(blocks) > 0 {
+			g, err := newGCCGraph(name, blocks, blocks[0].num)
+			if err != nil {
+				return errors.Wrapf(err, "unable to parse function %q", name)
+			}
+			gs = append(gs, g)
+		}
+		name, blocks, cur, inSuccs = "", nil, nil, false
+		return nil
+	}
+	// startBlock starts the basic block with the given number, unless current.
+	startBlock := func(num int) {
+		if cur != nil && cur.num == num {
+			return
+		}
+		cur = &gccBlock{num: num}
+		blocks = append(blocks, cur)
+	}
+	s := bufio.NewScanner(r)
+	for s.Scan() {
+		line := s.Text()
+		if m := reGCCFunc.FindStringSubmatch(line); m != nil {
+			if err := flush(); err != nil {
+				return nil, errors.WithStack(err)
+			}
+			name = m[1]
+			continue
+		}
+		if len(name) == 0 {
+			continue
+		}
+		if line == "}" {
+			// End of function.
+			if err := flush(); err != nil {
+				return nil, errors.WithStack(err)
+			}
+			continue
+		}
+		if m := reGCCBlockInfo.FindStringSubmatch(line); m != nil {
+			num, _ := strconv.Atoi(m[1])
+			startBlock(num)
+			inSuccs = false
+			continue
+		}
+		if m := reGCCBlock.FindStringSubmatch(line); m != nil {
+			num, _ := strconv.Atoi(m[1])
+			startBlock(num)
+			continue
+		}
+		if cur == nil {
+			// Declarations.
+			continue
+		}
+		if m := reGCCEdges.FindStringSubmatch(line); m != nil {
+			inSuccs = m[1] == "succ"
+			if inSuccs && cur.succs == nil {
+				cur.succs = []*gccSucc{}
+			}
+			line = ";; " + m[2]
+		}
+		if strings.HasPrefix(line, ";;") {
+			if inSuccs {
+				if succ, ok := parseGCCSucc(strings.TrimPrefix(line, ";;")); ok {
+					cur.succs = append(cur.succs, succ)
+				}
+			}
+			continue
+		}
+		inSuccs = false
+		cur.addLine(line)
+	}
+	if err := s.Err(); err != nil {
+		return nil, errors.WithStack(err)
+	}
+	if err := flush(); err != nil {
+		return nil, errors.WithStack(err)
+	}
+	return gs, nil
+}
+
+var (
+	// reGCCFunc is a regular expression matching function headers; e.g.
+	// ";; Function main (main, funcdef_no=0, ...)".
+	reGCCFunc = regexp.MustCompile(`^;; Function (\S+)`)
+	// reGCCBlockInfo is a regular expression matching basic block annotations;
+	// e.g. ";;   basic block 2, loop depth 0".
+	reGCCBlockInfo = regexp.MustCompile(`^;;\s+basic block ([0-9]+)\b`)
+	// reGCCEdges is a regular expression matching predecessor and successor
+	// annotations; e.g. ";;    succ:       3 [always]  (FALLTHRU,EXECUTABLE)".
+	reGCCEdges = regexp.MustCompile(`^;;\s+(pred|succ):\s*(.*)$`)
+	// reGCCSucc is a regular expression matching a successor annotation; e.g.
+	// "4 [50.0%]  (TRUE_VALUE,EXECUTABLE)".
+	reGCCSucc = regexp.MustCompile(`^\s*([0-9]+|EXIT)\b(?:.*\(([A-Z_,]+)\))?`)
+	// reGCCBlock is a regular expression matching basic block headers; e.g.
+	// "<bb 2> :", "<bb 2>:" or "<bb 2> [local count: 1073741824]:".
+	reGCCBlock = regexp.MustCompile(`^\s*<bb ([0-9]+)>\s*(?:\[[^\]]*\])?\s*:\s*$`)
+	// reGCCLabel is a regular expression matching labels; e.g. "<L0>:" or
+	// "<D.1234> [local count: 1073741824]:".
+	reGCCLabel = regexp.MustCompile(`^\s*<([^>]+)>\s*(?:\[[^\]]*\])?\s*:\s*$`)
+	// reGCCGoto is a regular expression matching goto statements; e.g.
+	// "goto <bb 4>; [INV]" or "goto <L1>;".
+	reGCCGoto = regexp.MustCompile(`goto <([^>]+)>`)
+	// reGCCCase is a regular expression matching cases of switch statements;
+	// e.g. "default: <L3>" or "case 1 ... 3: <bb 5>".
+	reGCCCase = regexp.MustCompile(`(default|case ([^:<>]+)): <([^>]+)>`)
+)
+
+// parseGCCSucc parses the given successor annotation, and returns the
+// successor and a boolean variable indicating success. Edges to EXIT are
+// omitted.
+func parseGCCSucc(s string) (*gccSucc, bool) {
+	m := reGCCSucc.FindStringSubmatch(s)
+	if m == nil || m[1] == "EXIT" {
+		return nil, false
+	}
+	num, _ := strconv.Atoi(m[1])
+	succ := &gccSucc{num: num}
+	for _, flag := range strings.Split(m[2], ",") {
+		switch flag {
+		case "TRUE_VALUE":
+			succ.label = "true"
+		case "FALSE_VALUE":
+			succ.label = "false"
+		}
+	}
+	return succ, true
+}
+
+// addLine adds the given line of a basic block; either a label or a GIMPLE
+// statement.
+func (b *gccBlock) addLine(line string) {
+	if reGCCBlock.MatchString(line) {
+		return
+	}
+	if m := reGCCLabel.FindStringSubmatch(line); m != nil {
+		b.labels = append(b.labels, m[1])
+		return
+	}
+	line = strings.TrimSpace(line)
+	if len(line) == 0 || strings.HasPrefix(line, "#") {
+		// Skip empty lines and debug statements.
+		return
+	}
+	b.stmts = append(b.stmts, line)
+}
+
+// newGCCGraph returns a new control flow graph of the given function, based on
+// its basic blocks in order of occurrence and the number of its entry block.
+func newGCCGraph(name string, blocks []*gccBlock, entry int) (*Graph, error) {
+	if len(blocks) == 0 {
+		return nil, errors.New("no basic blocks in function")
+	}
+	g := NewGraph()
+	g.id = name
+	// nodes maps from basic block number to node; and labels from label name to
+	// basic block number.
+	nodes := make(map[int]*Node)
+	labels := make(map[string]int)
+	for _, b := range blocks {
+		nodes[b.num] = nodeWithName(g, fmt.Sprintf("bb%d", b.num))
+		for _, label := range b.labels {
+			labels[label] = b.num
+		}
+	}
+	n, ok := nodes[entry]
+	if !ok {
+		return nil, errors.Errorf("unable to locate entry basic block %d", entry)
+	}
+	g.SetEntry(n)
+	for i, b := range blocks {
+		var next *gccBlock
+		if i+1 < len(blocks) {
+			next = blocks[i+1]
+		}
+		inferred, err := b.inferSuccs(next, labels)
+		if err != nil {
+			return nil, errors.Wrapf(err, "invalid basic block %d", b.num)
+		}
+		succs := inferred
+		if b.succs != nil {
+			// Successors recorded by the dump take precedence; edge labels are
+			// inferred from the statements of the basic block if not recorded.
+			succs = b.succs
+			if len(succs) == 2 && len(succs[0].label)+len(succs[1].label) > 0 {
+				// Infer the label of the other edge if only one edge is labelled
+				// (e.g. back edges drawn without branch color).
+				t, f := succs[0], succs[1]
+				if t.label == "false" || f.label == "true" {
+					t, f = f, t
+				}
+				succs = []*gccSucc{{num: t.num, label: "true"}, {num: f.num, label: "false"}}
+			} else if sameSuccs(succs, inferred) {
+				succs = inferred
+			}
+		}
+		from := nodes[b.num]
+		for _, succ := range succs {
+			to, ok := nodes[succ.num]
+			if !ok {
+				return nil, errors.Errorf("unable to locate successor basic block %d of basic block %d", succ.num, b.num)
+			}
+			switch succ.label {
+			case "", "true", "false":
+				edgeWithLabel(g, from, to, succ.label)
+			default:
+				caseEdge(g, from, to, succ.label)
+			}
+		}
+	}
+	return g, nil
+}
+
+// sameSuccs reports whether the given lists of successors have the same set of
+// basic blocks, ignoring edge labels.
+func sameSuccs(a, b []*gccSucc) bool {
+	nums := func(succs []*gccSucc) map[int]bool {
+		m := make(map[int]bool)
+		for _, succ := range succs {
+			m[succ.num] = true
+		}
+		return m
+	}
+	x, y := nums(a), nums(b)
+	if len(x) != len(y) {
+		return false
+	}
+	for num := range x {
+		if !y[num] {
+			return false
+		}
+	}
+	return true
+}
+
+// inferSuccs returns the successors of the basic block, as inferred from its
+// goto, if, switch and return statements; or the next basic block if none
+// present. Goto targets are either basic blocks (e.g. "bb 3") or labels.
+func (b *gccBlock) inferSuccs(next *gccBlock, labels map[string]int) ([]*gccSucc, error) {
+	// target returns the basic block number of the given goto target.
+	target := func(s string) (int, error) {
+		if strings.HasPrefix(s, "bb ") {
+			num, err := strconv.Atoi(s[len("bb "):])
+			if err != nil {
+				return 0, errors.WithStack(err)
+			}
+			return num, nil
+		}
+		num, ok := labels[s]
+		if !ok {
+			return 0, errors.Errorf("unable to locate basic block of label %q", s)
+		}
+		return num, nil
+	}
+	for _, stmt := range b.stmts {
+		if !strings.HasPrefix(stmt, "switch ") {
+			continue
+		}
+		var succs []*gccSucc
+		for _, m := range reGCCCase.FindAllStringSubmatch(stmt, -1) {
+			num, err := target(m[3])
+			if err != nil {
+				return nil, errors.WithStack(err)
+			}
+			label := "default case"
+			if m[1] != "default" {
+				label = fmt.Sprintf("case (x=%s)", strings.TrimSpace(m[2]))
+			}
+			succs = append(succs, &gccSucc{num: num, label: label})
+		}
+		return succs, nil
+	}
+	var (
+		gotos  []int
+		isCond bool
+	)
+	for _, stmt := range b.stmts {
+		if strings.HasPrefix(stmt, "if ") || strings.HasPrefix(stmt, "if(") {
+			isCond = true
+		}
+		for _, m := range reGCCGoto.FindAllStringSubmatch(stmt, -1) {
+			num, err := target(m[1])
+			if err != nil {
+				return nil, errors.WithStack(err)
+			}
+			gotos = append(gotos, num)
+		}
+	}
+	switch {
+	case isCond && len(gotos) == 2 && gotos[0] != gotos[1]:
+		return []*gccSucc{{num: gotos[0], label: "true"}, {num: gotos[1], label: "false"}}, nil
+	case isCond && len(gotos) == 2, len(gotos) == 1:
+		return []*gccSucc{{num: gotos[0]}}, nil
+	case len(gotos) > 0:
+		return nil, errors.Errorf("support for %d goto statements in basic block not yet implemented", len(gotos))
+	}
+	for _, stmt := range b.stmts {
+		if stmt == "return;" || strings.HasPrefix(stmt, "return ") {
+			return nil, nil
+		}
+	}
+	// Fall through to the next basic block.
+	if next == nil {
+		return nil, nil
+	}
+	return []*gccSucc{{num: next.num}}, nil
+}
+
+// trimQuotes returns the given DOT string without surrounding quotes, keeping
+// escape sequences.
+func trimQuotes(s string) string {
+	if len(s) >= 2 && strings.HasPrefix(s, `"`) && strings.HasSuffix(s, `"`) {
+		return s[1 : len(s)-1]
+	}
+	return s
+}
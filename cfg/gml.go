@@ -0,0 +1,376 @@
+package cfg
+
+import (
+	"fmt"
+	"html"
+	"io"
+	"io/ioutil"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+	"gonum.org/v1/gonum/graph"
+)
+
+// GML returns the string representation of the graph in GML format.
+//
+// Nodes are given consecutive integer IDs, and the label of each node is its
+// name. The entry node is recorded by the "entry" key of nodes. DOT attributes
+// of nodes and edges are recorded in a nested "attrs" list; edge labels are
+// also recorded by the "label" key of edges, for display in graph editors. DOT
+// attributes with keys which are not valid GML keys (e.g. "my-key") are recorded
+// as "attr" lists within the "attrs" list, holding the key and value of the
+// attribute.
+func (g *Graph) GML() string {
+	buf := &strings.Builder{}
+	buf.WriteString("graph [\n")
+	buf.WriteString("\tdirected 1\n")
+	if len(g.id) > 0 {
+		fmt.Fprintf(buf, "\tlabel %s\n", gmlQuote(g.id))
+	}
+	nodes := sortByID(graph.NodesOf(g.Nodes()))
+	// ids maps from node ID to GML node ID.
+	ids := make(map[int64]int)
+	for i, n := range nodes {
+		ids[n.ID()] = i
+		buf.WriteString("\tnode [\n")
+		fmt.Fprintf(buf, "\t\tid %d\n", i)
+		fmt.Fprintf(buf, "\t\tlabel %s\n", gmlQuote(n.name))
+		if n.entry {
+			buf.WriteString("\t\tentry 1\n")
+		}
+		attrs := make(Attrs)
+		for key, val := range n.Attrs {
+			// The entry node is recorded by the entry key, not by its DOT label.
+			if n.entry && key == "label" && val == "entry" {
+				continue
+			}
+			attrs[key] = val
+		}
+		writeGMLAttrs(buf, attrs)
+		buf.WriteString("\t]\n")
+	}
+	for _, from := range nodes {
+		for _, to := range sortByID(graph.NodesOf(g.From(from.ID()))) {
+			e := edge(g.Edge(from.ID(), to.ID()))
+			buf.WriteString("\tedge [\n")
+			fmt.Fprintf(buf, "\t\tsource %d\n", ids[from.ID()])
+			fmt.Fprintf(buf, "\t\ttarget %d\n", ids[to.ID()])
+			if label, ok := e.Attrs["label"]; ok {
+				fmt.Fprintf(buf, "\t\tlabel %s\n", gmlQuote(label))
+			}
+			writeGMLAttrs(buf, e.Attrs)
+			buf.WriteString("\t]\n")
+		}
+	}
+	buf.WriteString("]")
+	return buf.String()
+}
+
+// writeGMLAttrs writes the given DOT attributes to buf as a nested GML list,
+// if not empty.
+func writeGMLAttrs(buf *strings.Builder, attrs Attrs) {
+	if len(attrs) == 0 {
+		return
+	}
+	buf.WriteString("\t\tattrs [\n")
+	for _, key := range sortedKeys(attrs) {
+		if !isGMLKey(key) {
+			fmt.Fprintf(buf, "\t\t\tattr [\n\t\t\t\tkey %s\n\t\t\t\tvalue %s\n\t\t\t]\n", gmlQuote(key), gmlQuote(attrs[key]))
+			continue
+		}
+		fmt.Fprintf(buf, "\t\t\t%s %s\n", key, gmlQuote(attrs[key]))
+	}
+	buf.WriteString("\t\t]\n")
+}
+
+// gmlQuote returns the given string as a GML string literal. Quotes and
+// ampersands are escaped as HTML entities.
+func gmlQuote(s string) string {
+	s = strings.Replace(s, "&", "&amp;", -1)
+	s = strings.Replace(s, `"`, "&quot;", -1)
+	return `"` + s + `"`
+}
+
+// ParseGML parses the given GML file into a control flow graph, reading from r.
+func ParseGML(r io.Reader) (*Graph, error) {
+	buf, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	return ParseGMLBytes(buf)
+}
+
+// ParseGMLFile parses the given GML file into a control flow graph, reading
+// from path.
+func ParseGMLFile(path string) (*Graph, error) {
+	buf, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	return ParseGMLBytes(buf)
+}
+
+// ParseGMLBytes parses the given GML file into a control flow graph, reading
+// from b.
+//
+// The name of each node is its label if present, and its ID otherwise. DOT
+// attributes are read from the nested "attrs" list of nodes and edges; edges
+// without such a list are given the DOT label of their "label" key. The entry
+// node is identified by a non-zero "entry" key. Other keys (e.g. graphics of
+// yEd) are ignored.
+func ParseGMLBytes(b []byte) (*Graph, error) {
+	p := &gmlParser{s: string(b)}
+	top, err := p.parseList(false)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	gl, ok := top.list("graph")
+	if !ok {
+		return nil, errors.New("unable to locate graph in GML file")
+	}
+	if directed, ok := gl.value("directed"); ok && directed == "0" {
+		return nil, errors.New("support for undirected GML graphs not yet implemented")
+	}
+	g := NewGraph()
+	if label, ok := gl.value("label"); ok {
+		g.id = label
+	}
+	// nodes maps from GML node ID to node.
+	nodes := make(map[string]*Node)
+	for _, pair := range gl {
+		if pair.key != "node" || pair.list == nil {
+			continue
+		}
+		nl := pair.list
+		id, ok := nl.value("id")
+		if !ok {
+			return nil, errors.New("invalid node; missing node ID")
+		}
+		if _, ok := nodes[id]; ok {
+			return nil, errors.Errorf("invalid node; node ID %s already present in graph", id)
+		}
+		name := id
+		if label, ok := nl.value("label"); ok && len(label) > 0 {
+			name = label
+		}
+		if _, ok := g.NodeWithName(name); ok {
+			return nil, errors.Errorf("invalid node; node name %q already present in graph", name)
+		}
+		n := g.NewNodeWithName(name)
+		if entry, ok := nl.value("entry"); ok && entry != "0" {
+			n.entry = true
+		}
+		if attrs, ok := nl.list("attrs"); ok {
+			attrs.setAttrs(n.Attrs)
+		}
+		if n.entry && g.entry != nil {
+			return nil, errors.Errorf("ambiguous entry node; nodes %q and %q flagged as entry", node(g.entry).name, n.name)
+		}
+		nodes[id] = n
+		g.AddNode(n)
+	}
+	for _, pair := range gl {
+		if pair.key != "edge" || pair.list == nil {
+			continue
+		}
+		el := pair.list
+		source, _ := el.value("source")
+		from, ok := nodes[source]
+		if !ok {
+			return nil, errors.Errorf("unable to locate source node %q of edge", source)
+		}
+		target, _ := el.value("target")
+		to, ok := nodes[target]
+		if !ok {
+			return nil, errors.Errorf("unable to locate target node %q of edge", target)
+		}
+		e := edge(g.NewEdge(from, to))
+		if attrs, ok := el.list("attrs"); ok {
+			attrs.setAttrs(e.Attrs)
+		} else if label, ok := el.value("label"); ok {
+			e.Attrs["label"] = label
+		}
+		g.SetEdge(e)
+	}
+	if g.entry == nil {
+		return nil, errors.New("unable to locate entry node in GML file")
+	}
+	return g, nil
+}
+
+// ParseGMLString parses the given GML file into a control flow graph, reading
+// from s.
+func ParseGMLString(s string) (*Graph, error) {
+	return ParseGMLBytes([]byte(s))
+}
+
+// gmlList is a list of key-value pairs in GML.
+type gmlList []*gmlPair
+
+// gmlPair is a key-value pair in GML.
+type gmlPair struct {
+	// Key of the pair.
+	key string
+	// Value of the pair; a number or unquoted string, if not a list.
+	val string
+	// List value of the pair; or nil if not a list.
+	list gmlList
+}
+
+// value returns the first non-list value of the given key, and a boolean
+// variable indicating success.
+func (l gmlList) value(key string) (string, bool) {
+	for _, pair := range l {
+		if pair.key == key && pair.list == nil {
+			return pair.val, true
+		}
+	}
+	return "", false
+}
+
+// list returns the first list value of the given key, and a boolean variable
+// indicating success.
+func (l gmlList) list(key string) (gmlList, bool) {
+	for _, pair := range l {
+		if pair.key == key && pair.list != nil {
+			return pair.list, true
+		}
+	}
+	return nil, false
+}
+
+// setAttrs stores the non-list values of the list as DOT attributes, and the
+// key and value of each "attr" list.
+func (l gmlList) setAttrs(attrs Attrs) {
+	for _, pair := range l {
+		if pair.list == nil {
+			attrs[pair.key] = pair.val
+			continue
+		}
+		if pair.key != "attr" {
+			continue
+		}
+		key, ok := pair.list.value("key")
+		if !ok {
+			continue
+		}
+		val, _ := pair.list.value("value")
+		attrs[key] = val
+	}
+}
+
+// gmlParser is a parser of GML files.
+type gmlParser struct {
+	// GML source.
+	s string
+	// Current offset into the source.
+	pos int
+}
+
+// parseList parses a list of key-value pairs, terminated by ']' if nested and
+// by the end of the source otherwise.
+func (p *gmlParser) parseList(nested bool) (gmlList, error) {
+	// Empty lists are represented by a non-nil list, to distinguish them from
+	// non-list values.
+	l := gmlList{}
+	for {
+		p.skipSpace()
+		if p.pos >= len(p.s) {
+			if nested {
+				return nil, errors.New("unexpected end of GML file; missing ']'")
+			}
+			return l, nil
+		}
+		if p.s[p.pos] == ']' {
+			if !nested {
+				return nil, errors.Errorf("unexpected ']' at offset %d of GML file", p.pos)
+			}
+			p.pos++
+			return l, nil
+		}
+		key := p.scan(isGMLKeyChar)
+		if len(key) == 0 {
+			return nil, errors.Errorf("invalid key at offset %d of GML file; unexpected %q", p.pos, p.s[p.pos])
+		}
+		pair := &gmlPair{key: key}
+		p.skipSpace()
+		if p.pos >= len(p.s) {
+			return nil, errors.Errorf("unexpected end of GML file; missing value of key %q", key)
+		}
+		switch c := p.s[p.pos]; {
+		case c == '[':
+			p.pos++
+			list, err := p.parseList(true)
+			if err != nil {
+				return nil, errors.WithStack(err)
+			}
+			pair.list = list
+		case c == '"':
+			end := strings.IndexByte(p.s[p.pos+1:], '"')
+			if end == -1 {
+				return nil, errors.Errorf("unterminated string at offset %d of GML file", p.pos)
+			}
+			pair.val = html.UnescapeString(p.s[p.pos+1 : p.pos+1+end])
+			p.pos += end + 2
+		default:
+			val := p.scan(isGMLNumberChar)
+			if _, err := strconv.ParseFloat(val, 64); err != nil {
+				return nil, errors.Errorf("invalid value of key %q at offset %d of GML file", key, p.pos)
+			}
+			pair.val = val
+		}
+		l = append(l, pair)
+	}
+}
+
+// skipSpace skips whitespace and comments; lines starting with '#'.
+func (p *gmlParser) skipSpace() {
+	for p.pos < len(p.s) {
+		switch p.s[p.pos] {
+		case ' ', '\t', '\n', '\r':
+			p.pos++
+		case '#':
+			end := strings.IndexByte(p.s[p.pos:], '\n')
+			if end == -1 {
+				p.pos = len(p.s)
+				return
+			}
+			p.pos += end
+		default:
+			return
+		}
+	}
+}
+
+// scan scans the longest run of characters satisfying valid.
+func (p *gmlParser) scan(valid func(c byte) bool) string {
+	start := p.pos
+	for p.pos < len(p.s) && valid(p.s[p.pos]) {
+		p.pos++
+	}
+	return p.s[start:p.pos]
+}
+
+// isGMLKey reports whether s is a valid GML key.
+func isGMLKey(s string) bool {
+	if len(s) == 0 {
+		return false
+	}
+	for i := 0; i < len(s); i++ {
+		if !isGMLKeyChar(s[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+// isGMLKeyChar reports whether c may occur in a GML key.
+func isGMLKeyChar(c byte) bool {
+	return 'a' <= c && c <= 'z' || 'A' <= c && c <= 'Z' || '0' <= c && c <= '9' || c == '_'
+}
+
+// isGMLNumberChar reports whether c may occur in a GML number.
+func isGMLNumberChar(c byte) bool {
+	return '0' <= c && c <= '9' || c == '-' || c == '+' || c == '.' || c == 'e' || c == 'E'
+}
@@ -0,0 +1,144 @@
+package cfg
+
+import (
+	"bufio"
+	"io"
+	"regexp"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+var (
+	// reGoSSABlock is a regular expression matching basic block headers of Go
+	// SSA functions in text form; e.g. "  b2: ← b1 b3".
+	reGoSSABlock = regexp.MustCompile(`^\s+(b[0-9]+):`)
+	// reGoSSAValue is a regular expression matching values of Go SSA functions
+	// in text form; e.g. "    v7 (12) = Arg <int> {x}".
+	reGoSSAValue = regexp.MustCompile(`^\s+v[0-9]+\b`)
+	// reGoSSASucc is a regular expression matching successor blocks.
+	reGoSSASucc = regexp.MustCompile(`\bb[0-9]+\b`)
+)
+
+// goSSABlock is a basic block of a Go SSA function in text form.
+type goSSABlock struct {
+	// Block name (e.g. "b1").
+	name string
+	// Block kind (e.g. "Plain", "If" or "Ret").
+	kind string
+	// Names of successor blocks.
+	succs []string
+}
+
+// ParseGoSSA parses the given Go SSA functions in text form (as dumped by the
+// Go compiler through GOSSAFUNC or -d=ssa/<pass>/dump) into one control flow
+// graph per function, reading from r.
+//
+//    f func(int) int
+//      b1:
+//        v1 (?) = InitMem <mem>
+//        ...
+//        If v9 → b2 b3 (likely) (12)
+//      b2: ← b1
+//        ...
+//
+// Nodes are named after their block (e.g. "b1"), and the first block of each
+// function is used as the entry node. The successors of blocks with two
+// successors (e.g. If, or conditional blocks of lowered architecture-specific
+// form) are taken if the control value is true and false respectively; except
+// for First and Defer blocks, which are unconditional.
+func ParseGoSSA(r io.Reader) ([]*Graph, error) {
+	var gs []*Graph
+	var (
+		// Name of the current function; or empty if outside of function.
+		name string
+		// Blocks of the current function.
+		blocks []*goSSABlock
+	)
+	flush := func() error {
+		if len(blocks) > 0 {
+			g, err := newGoSSAGraph(name, blocks)
+			if err != nil {
+				return errors.Wrapf(err, "unable to parse function %q", name)
+			}
+			gs = append(gs, g)
+		}
+		name, blocks = "", nil
+		return nil
+	}
+	s := bufio.NewScanner(r)
+	for s.Scan() {
+		line := s.Text()
+		switch {
+		case len(strings.TrimSpace(line)) == 0:
+			// Skip empty lines.
+		case !strings.HasPrefix(line, " ") && !strings.HasPrefix(line, "\t"):
+			// Function header.
+			if err := flush(); err != nil {
+				return nil, errors.WithStack(err)
+			}
+			name = strings.Fields(line)[0]
+		case len(name) == 0:
+			// Skip lines outside of function.
+		case reGoSSABlock.MatchString(line):
+			m := reGoSSABlock.FindStringSubmatch(line)
+			blocks = append(blocks, &goSSABlock{name: m[1]})
+		case len(blocks) == 0, reGoSSAValue.MatchString(line):
+			// Skip values.
+		case strings.HasPrefix(strings.TrimSpace(line), "name "):
+			// Skip names of values; e.g. "name x[int]: [v7]".
+		default:
+			// Block control; e.g. "If v9 → b2 b3 (likely)".
+			b := blocks[len(blocks)-1]
+			b.kind = strings.Fields(line)[0]
+			arrow := strings.Index(line, "→")
+			if arrow == -1 {
+				arrow = strings.Index(line, "->")
+			}
+			if arrow != -1 {
+				b.succs = reGoSSASucc.FindAllString(line[arrow:], -1)
+			}
+		}
+	}
+	if err := s.Err(); err != nil {
+		return nil, errors.WithStack(err)
+	}
+	if err := flush(); err != nil {
+		return nil, errors.WithStack(err)
+	}
+	return gs, nil
+}
+
+// newGoSSAGraph returns a new control flow graph of the given Go SSA function,
+// based on its blocks in order of occurrence.
+func newGoSSAGraph(name string, blocks []*goSSABlock) (*Graph, error) {
+	g := NewGraph()
+	g.id = name
+	for _, b := range blocks {
+		if _, ok := g.NodeWithName(b.name); ok {
+			return nil, errors.Errorf("block %q already present in function", b.name)
+		}
+		nodeWithName(g, b.name)
+	}
+	g.SetEntry(g.nodeWithName(blocks[0].name))
+	for _, b := range blocks {
+		from := g.nodeWithName(b.name)
+		var succs []*Node
+		for _, succ := range b.succs {
+			to, ok := g.NodeWithName(succ)
+			if !ok {
+				return nil, errors.Errorf("unable to locate successor %q of block %q", succ, b.name)
+			}
+			succs = append(succs, to)
+		}
+		switch {
+		case len(succs) == 2 && b.kind != "First" && b.kind != "Defer":
+			condEdges(g, from, succs[0], succs[1])
+		default:
+			for _, succ := range succs {
+				edgeWithLabel(g, from, succ, "")
+			}
+		}
+	}
+	return g, nil
+}
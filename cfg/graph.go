@@ -9,6 +9,7 @@ import (
 	"strconv"
 	"strings"
 
+	"github.com/graphism/exp/logic"
 	"github.com/graphism/simple"
 	"github.com/llir/llvm/ir"
 	"github.com/llir/llvm/ir/value"
@@ -39,6 +40,9 @@ type Graph struct {
 	entry graph.Node
 	// nodes maps from node name to graph node.
 	nodes map[string]*Node
+	// nextID is one greater than the greatest ID of the nodes added to the
+	// graph, including removed nodes.
+	nextID int64
 }
 
 // NewGraph returns a new control flow graph.
@@ -72,16 +76,15 @@ func NewGraphFromFunc(f *ir.Func) *Graph {
 		case *ir.TermCondBr:
 			t := nodeWithName(g, term.TargetTrue.(value.Named).Name())
 			f := nodeWithName(g, term.TargetFalse.(value.Named).Name())
-			edgeWithLabel(g, from, t, "true")
-			edgeWithLabel(g, from, f, "false")
+			condEdges(g, from, t, f)
 		case *ir.TermSwitch:
 			for _, c := range term.Cases {
 				to := nodeWithName(g, c.Target.(value.Named).Name())
 				label := fmt.Sprintf("case (x=%v)", c.X.Ident())
-				edgeWithLabel(g, from, to, label)
+				caseEdge(g, from, to, label)
 			}
 			to := nodeWithName(g, term.TargetDefault.(value.Named).Name())
-			edgeWithLabel(g, from, to, "default case")
+			caseEdge(g, from, to, "default case")
 		case *ir.TermUnreachable:
 			// nothing to do.
 		default:
@@ -119,6 +122,18 @@ func edgeWithLabel(g *Graph, from, to *Node, label string) *Edge {
 	return e
 }
 
+// caseLabel returns the given case label, prefixed by the label of the edge
+// between the specified nodes if present; cases sharing the same target are
+// recorded as a comma-separated list of labels on a single edge.
+func caseLabel(g *Graph, from, to *Node, label string) string {
+	if e := g.Edge(from.ID(), to.ID()); e != nil {
+		if prev := edge(e).Attrs["label"]; len(prev) > 0 {
+			return prev + ", " + label
+		}
+	}
+	return label
+}
+
 // String returns the string representation of the graph in Graphviz DOT format.
 func (g *Graph) String() string {
 	data, err := dot.Marshal(g, g.DOTID(), "", "\t")
@@ -259,6 +274,9 @@ func (g *Graph) NewNode() graph.Node {
 func (g *Graph) AddNode(n graph.Node) {
 	nn := node(n)
 	g.DirectedGraph.AddNode(nn)
+	if id := nn.ID(); id >= g.nextID {
+		g.nextID = id + 1
+	}
 	if nn.entry {
 		if g.entry != nil && nn != g.entry {
 			panic(fmt.Errorf("entry node already set in graph; prev entry node %#v, new entry node %#v", g.entry, nn))
@@ -355,6 +373,25 @@ type Node struct {
 	SwitchHead *Node
 	// Switch follow node.
 	SwitchFollow *Node
+	// Compound condition of the 2-way node, under which the true branch is
+	// taken; or nil if not a compound condition. The expression tree records
+	// the nesting of the merged conditions, over the condition symbols of
+	// CondNodes.
+	Cond logic.Expr
+	// 2-way nodes merged into the compound condition, in order of evaluation.
+	CondNodes []*Node
+	// Original nodes represented by the node, through any number of merges; or
+	// nil if not a merged node.
+	origins []*Node
+}
+
+// Origins returns the original nodes represented by the node, through any
+// number of merges, sorted by name; or the node itself if not a merged node.
+func (n *Node) Origins() []*Node {
+	if n.origins == nil {
+		return []*Node{n}
+	}
+	return n.origins
 }
 
 //go:generate stringer -type LoopType -linecomment
@@ -446,6 +483,30 @@ type Edge struct {
 	Attrs
 }
 
+// Cases returns the case values of an edge from the header node of an n-way
+// conditional, based on its edge label, and a boolean variable indicating
+// whether the edge represents the default case.
+func (e *Edge) Cases() (values []string, isDefault bool, err error) {
+	const prefix, suffix = "case (x=", ")"
+	label := e.Attrs["label"]
+	if s, err := strconv.Unquote(label); err == nil {
+		label = s
+	}
+	// Cases sharing the same target are recorded as a comma-separated list of
+	// labels.
+	for _, l := range strings.Split(label, ", ") {
+		switch {
+		case l == "default case":
+			isDefault = true
+		case strings.HasPrefix(l, prefix) && strings.HasSuffix(l, suffix):
+			values = append(values, l[len(prefix):len(l)-len(suffix)])
+		default:
+			return nil, false, errors.Errorf("unable to parse case label %q of edge (%q -> %q)", label, node(e.From()).DOTID(), node(e.To()).DOTID())
+		}
+	}
+	return values, isDefault, nil
+}
+
 // --- [ encoding.Attributer ] -------------------------------------------------
 
 // Attributes returns the DOT attributes of the edge.
@@ -1,12 +1,16 @@
 package cfg
 
 import (
+	"encoding/json"
 	"fmt"
+	"io"
 	"io/ioutil"
+	"os"
 	"reflect"
 	"strings"
 	"testing"
 
+	"github.com/graphism/exp/logic"
 	"gonum.org/v1/gonum/graph"
 )
 
@@ -36,6 +40,283 @@ func TestRoundTrip(t *testing.T) {
 	}
 }
 
+func TestRoundTripGraphML(t *testing.T) {
+	golden := []struct {
+		path     string
+		wantPath string
+	}{
+		{path: "testdata/a.dot", wantPath: "testdata/a.dot.graphml.golden"},
+	}
+	for _, gold := range golden {
+		buf, err := ioutil.ReadFile(gold.path)
+		if err != nil {
+			t.Errorf("%q; unable to read file; %v", gold.path, err)
+			continue
+		}
+		want := strings.TrimSpace(string(buf))
+		buf, err = ioutil.ReadFile(gold.wantPath)
+		if err != nil {
+			t.Errorf("%q; unable to read file; %v", gold.wantPath, err)
+			continue
+		}
+		wantGraphML := strings.TrimSpace(string(buf))
+		g, err := ParseString(want)
+		if err != nil {
+			t.Errorf("%q; unable to parse file; %v", gold.path, err)
+			continue
+		}
+		// Export.
+		gotGraphML := g.GraphML()
+		if gotGraphML != wantGraphML {
+			t.Errorf("%q; GraphML output mismatch; expected `%s`, got `%s`", gold.path, wantGraphML, gotGraphML)
+			continue
+		}
+		// Import.
+		g, err = ParseGraphMLString(gotGraphML)
+		if err != nil {
+			t.Errorf("%q; unable to parse GraphML file; %v", gold.path, err)
+			continue
+		}
+		got := g.String()
+		if got != want {
+			t.Errorf("%q; output mismatch; expected `%s`, got `%s`", gold.path, want, got)
+			continue
+		}
+	}
+}
+
+func TestRoundTripGML(t *testing.T) {
+	golden := []struct {
+		path     string
+		wantPath string
+	}{
+		{path: "testdata/a.dot", wantPath: "testdata/a.dot.gml.golden"},
+		// Attribute key which is not a valid GML key.
+		{path: "testdata/attrs.dot", wantPath: "testdata/attrs.dot.gml.golden"},
+	}
+	for _, gold := range golden {
+		buf, err := ioutil.ReadFile(gold.path)
+		if err != nil {
+			t.Errorf("%q; unable to read file; %v", gold.path, err)
+			continue
+		}
+		want := strings.TrimSpace(string(buf))
+		buf, err = ioutil.ReadFile(gold.wantPath)
+		if err != nil {
+			t.Errorf("%q; unable to read file; %v", gold.wantPath, err)
+			continue
+		}
+		wantGML := strings.TrimSpace(string(buf))
+		g, err := ParseString(want)
+		if err != nil {
+			t.Errorf("%q; unable to parse file; %v", gold.path, err)
+			continue
+		}
+		// Export.
+		gotGML := g.GML()
+		if gotGML != wantGML {
+			t.Errorf("%q; GML output mismatch; expected `%s`, got `%s`", gold.path, wantGML, gotGML)
+			continue
+		}
+		// Import.
+		g, err = ParseGMLString(gotGML)
+		if err != nil {
+			t.Errorf("%q; unable to parse GML file; %v", gold.path, err)
+			continue
+		}
+		got := g.String()
+		if got != want {
+			t.Errorf("%q; output mismatch; expected `%s`, got `%s`", gold.path, want, got)
+			continue
+		}
+	}
+}
+
+func TestParseAll(t *testing.T) {
+	golden := []struct {
+		path string
+		opts *ParseOptions
+		// Golden output path of each graph.
+		wantPaths []string
+	}{
+		{
+			path:      "testdata/funcs.dot",
+			opts:      &ParseOptions{NoPredsEntry: true},
+			wantPaths: []string{"testdata/funcs.dot.f.golden", "testdata/funcs.dot.g.golden"},
+		},
+	}
+	for _, gold := range golden {
+		gs, err := ParseAllFile(gold.path, gold.opts)
+		if err != nil {
+			t.Errorf("%q; unable to parse file; %v", gold.path, err)
+			continue
+		}
+		if len(gs) != len(gold.wantPaths) {
+			t.Errorf("%q; number of graphs mismatch; expected %d, got %d", gold.path, len(gold.wantPaths), len(gs))
+			continue
+		}
+		for i, g := range gs {
+			buf, err := ioutil.ReadFile(gold.wantPaths[i])
+			if err != nil {
+				t.Errorf("%q; unable to read file; %v", gold.wantPaths[i], err)
+				continue
+			}
+			want := strings.TrimSpace(string(buf))
+			got := g.String()
+			if got != want {
+				t.Errorf("%q; output mismatch of graph %d; expected `%s`, got `%s`", gold.path, i, want, got)
+			}
+		}
+	}
+}
+
+func TestParseEntry(t *testing.T) {
+	golden := []struct {
+		in   string
+		opts *ParseOptions
+		// Name of the entry node; or empty if an error is expected.
+		want string
+	}{
+		// Node labelled "entry".
+		{in: "digraph { A -> B; B [label=entry]; }", want: "B"},
+		// Node named "0".
+		{in: "digraph { 1 -> 0; }", want: "0"},
+		// No entry node.
+		{in: "digraph { A -> B; }", want: ""},
+		// Ambiguous labelled entry nodes.
+		{in: "digraph { A [label=entry]; B [label=entry]; }", want: ""},
+		// Explicit entry node.
+		{in: "digraph { A -> B; }", opts: &ParseOptions{Entry: "B"}, want: "B"},
+		// Explicit entry node not present.
+		{in: "digraph { A -> B; }", opts: &ParseOptions{Entry: "C"}, want: ""},
+		// Explicit entry node labelled "entry".
+		{in: "digraph { A [label=entry]; A -> B; }", opts: &ParseOptions{Entry: "A"}, want: "A"},
+		// Explicit entry node with a label other than "entry".
+		{in: `digraph { A [label="bb0"]; A -> B; }`, opts: &ParseOptions{Entry: "A"}, want: ""},
+		// Explicit entry node conflicting with labelled entry node.
+		{in: "digraph { A [label=entry]; A -> B; }", opts: &ParseOptions{Entry: "B"}, want: ""},
+		// Unique node without predecessors.
+		{in: "digraph { A -> B; B -> C; C -> B; }", opts: &ParseOptions{NoPredsEntry: true}, want: "A"},
+		// Labelled entry node takes precedence over node without predecessors.
+		{in: "digraph { A -> B; B -> A; C -> B; A [label=entry]; }", opts: &ParseOptions{NoPredsEntry: true}, want: "A"},
+		// Node without predecessors with a label other than "entry".
+		{in: `digraph { A [label="bb0"]; A -> B; }`, opts: &ParseOptions{NoPredsEntry: true}, want: ""},
+		// Ambiguous nodes without predecessors.
+		{in: "digraph { A -> C; B -> C; }", opts: &ParseOptions{NoPredsEntry: true}, want: ""},
+		// No node without predecessors.
+		{in: "digraph { A -> B; B -> A; }", opts: &ParseOptions{NoPredsEntry: true}, want: ""},
+		// Several graphs.
+		{in: "digraph { A [label=entry]; } digraph { B [label=entry]; }", want: ""},
+	}
+	for _, gold := range golden {
+		g, err := ParseBytesWithOptions([]byte(gold.in), gold.opts)
+		if len(gold.want) == 0 {
+			if err == nil {
+				t.Errorf("%q; expected error, got entry node %q", gold.in, node(g.Entry()).name)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%q; unable to parse graph; %v", gold.in, err)
+			continue
+		}
+		if got := node(g.Entry()).name; got != gold.want {
+			t.Errorf("%q; entry node mismatch; expected %q, got %q", gold.in, gold.want, got)
+		}
+		if !node(g.Entry()).entry {
+			t.Errorf("%q; entry node %q not marked as entry", gold.in, gold.want)
+		}
+		// Encoding the graph must not fail on the DOT label of the entry node.
+		if !strings.Contains(g.String(), "label=entry") {
+			t.Errorf("%q; entry node %q not labelled \"entry\" in DOT output", gold.in, gold.want)
+		}
+	}
+}
+
+func TestParseAmbiguousEntry(t *testing.T) {
+	const gml = `graph [
+	directed 1
+	node [
+		id 0
+		label "A"
+		entry 1
+	]
+	node [
+		id 1
+		label "B"
+		entry 1
+	]
+]`
+	if _, err := ParseGMLString(gml); err == nil || !strings.Contains(err.Error(), "ambiguous entry node") {
+		t.Errorf("GML; expected ambiguous entry node error, got %v", err)
+	}
+	const graphml = `<?xml version="1.0" encoding="UTF-8"?>
+<graphml xmlns="http://graphml.graphdrawing.org/xmlns">
+	<key id="entry" for="node" attr.name="entry" attr.type="boolean"></key>
+	<graph id="G" edgedefault="directed">
+		<node id="A">
+			<data key="entry">true</data>
+		</node>
+		<node id="B">
+			<data key="entry">true</data>
+		</node>
+	</graph>
+</graphml>`
+	if _, err := ParseGraphMLString(graphml); err == nil || !strings.Contains(err.Error(), "ambiguous entry node") {
+		t.Errorf("GraphML; expected ambiguous entry node error, got %v", err)
+	}
+}
+
+func TestImport(t *testing.T) {
+	golden := []struct {
+		path  string
+		parse func(r io.Reader) ([]*Graph, error)
+		// Names of the functions; the golden output of each function is stored
+		// in path.<name>.golden.
+		names []string
+	}{
+		{path: "testdata/gcc.c.015t.cfg", parse: ParseGCCDump, names: []string{"main", "sw"}},
+		{path: "testdata/gcc_blocks.c.015t.cfg", parse: ParseGCCDump, names: []string{"max"}},
+		{path: "testdata/gcc.c.015t.cfg.dot", parse: ParseGCCGraph, names: []string{"main", "max"}},
+		{path: "testdata/gossa.dump", parse: ParseGoSSA, names: []string{"sum", "first"}},
+		{path: "testdata/disasm.json", parse: ParseDisasmJSON, names: []string{"main", "loop"}},
+	}
+	for _, gold := range golden {
+		f, err := os.Open(gold.path)
+		if err != nil {
+			t.Errorf("%q; unable to open file; %v", gold.path, err)
+			continue
+		}
+		gs, err := gold.parse(f)
+		f.Close()
+		if err != nil {
+			t.Errorf("%q; unable to parse file; %v", gold.path, err)
+			continue
+		}
+		var names []string
+		for _, g := range gs {
+			names = append(names, g.DOTID())
+		}
+		if !reflect.DeepEqual(names, gold.names) {
+			t.Errorf("%q; function names mismatch; expected `%v`, got `%v`", gold.path, gold.names, names)
+			continue
+		}
+		for _, g := range gs {
+			wantPath := fmt.Sprintf("%s.%s.golden", gold.path, g.DOTID())
+			buf, err := ioutil.ReadFile(wantPath)
+			if err != nil {
+				t.Errorf("%q; unable to read file; %v", wantPath, err)
+				continue
+			}
+			want := strings.TrimSpace(string(buf))
+			got := g.String()
+			if got != want {
+				t.Errorf("%q; output mismatch of function %q; expected `%s`, got `%s`", gold.path, g.DOTID(), want, got)
+			}
+		}
+	}
+}
+
 func TestCopy(t *testing.T) {
 	golden := []struct {
 		path string
@@ -64,6 +345,133 @@ func TestCopy(t *testing.T) {
 	}
 }
 
+func TestDeepCopy(t *testing.T) {
+	golden := []struct {
+		path string
+		// Loop header assigned to each node before copy.
+		heads map[string]string
+	}{
+		{
+			path:  "testdata/sample.dot",
+			heads: map[string]string{"B13": "B13", "B14": "B13"},
+		},
+	}
+	for _, gold := range golden {
+		src, err := ParseFile(gold.path)
+		if err != nil {
+			t.Errorf("%q; unable to parse file; %v", gold.path, err)
+			continue
+		}
+		for name, head := range gold.heads {
+			src.nodeWithName(name).LoopHead = src.nodeWithName(head)
+		}
+		want := src.String()
+		dst := NewGraph()
+		m := DeepCopy(dst, src)
+		if got := dst.String(); got != want {
+			t.Errorf("%q; output mismatch; expected `%s`, got `%s`", gold.path, want, got)
+			continue
+		}
+		if len(m) != src.Nodes().Len() {
+			t.Errorf("%q; number of mapped nodes mismatch; expected %d, got %d", gold.path, src.Nodes().Len(), len(m))
+		}
+		for old, n := range m {
+			if old == n {
+				t.Errorf("%q; node %q shared between source and destination", gold.path, old.name)
+			}
+			if dst.Node(old.ID()) != n {
+				t.Errorf("%q; node ID mismatch of %q", gold.path, old.name)
+			}
+			if old.LoopHead != nil && n.LoopHead != m[old.LoopHead] {
+				t.Errorf("%q; loop header of %q not remapped", gold.path, old.name)
+			}
+			// Edit the destination node.
+			n.Attrs["color"] = "red"
+		}
+		if got := src.String(); got != want {
+			t.Errorf("%q; source graph modified through copy; expected `%s`, got `%s`", gold.path, want, got)
+		}
+	}
+}
+
+func TestJSON(t *testing.T) {
+	golden := []struct {
+		path     string
+		wantPath string
+		// Nodes collapsed into a compound condition before encoding.
+		cond map[string]bool
+	}{
+		{
+			path:     "testdata/sample.dot",
+			wantPath: "testdata/sample.dot.json.golden",
+			cond:     map[string]bool{"B7": true, "B8": true},
+		},
+	}
+	for _, gold := range golden {
+		// Parse input.
+		g, err := ParseFile(gold.path)
+		if err != nil {
+			t.Errorf("%q; unable to parse file; %v", gold.path, err)
+			continue
+		}
+		// Parse golden output.
+		buf, err := ioutil.ReadFile(gold.wantPath)
+		if err != nil {
+			t.Errorf("%q; unable to read file; %v", gold.path, err)
+			continue
+		}
+		want := strings.TrimSpace(string(buf))
+		// Annotate graph.
+		n := g.Collapse(gold.cond, "B7_cond")
+		n.Cond = logic.NewAnd(logic.Sym("B7"), logic.NewNot(logic.Sym("B8")))
+		n.CondNodes = n.Origins()
+		edge(g.Edge(n.ID(), g.nodeWithName("B9").ID())).Attrs["label"] = "true"
+		edge(g.Edge(n.ID(), g.nodeWithName("B10").ID())).Attrs["label"] = "false"
+		InitDFSOrder(g)
+		head, latch := g.nodeWithName("B13"), g.nodeWithName("B14")
+		head.LoopType = LoopTypePostTest
+		head.NBackEdges = 1
+		head.Latch = latch
+		head.LoopFollow = g.nodeWithName("B15")
+		latch.IsLatch = true
+		for _, n := range []*Node{head, latch} {
+			n.LoopHead = head
+		}
+		g.nodeWithName("B1").IfFollow = g.nodeWithName("B5")
+		// Encode.
+		data, err := json.MarshalIndent(g, "", "\t")
+		if err != nil {
+			t.Errorf("%q; unable to encode graph; %v", gold.path, err)
+			continue
+		}
+		got := string(data)
+		if got != want {
+			t.Errorf("%q; output mismatch; expected `%s`, got `%s`", gold.path, want, got)
+			continue
+		}
+		// Decode.
+		dec := NewGraph()
+		if err := json.Unmarshal(data, dec); err != nil {
+			t.Errorf("%q; unable to decode graph; %v", gold.path, err)
+			continue
+		}
+		if got, want := dec.String(), g.String(); got != want {
+			t.Errorf("%q; DOT output mismatch; expected `%s`, got `%s`", gold.path, want, got)
+		}
+		data, err = json.MarshalIndent(dec, "", "\t")
+		if err != nil {
+			t.Errorf("%q; unable to re-encode graph; %v", gold.path, err)
+			continue
+		}
+		if got := string(data); got != want {
+			t.Errorf("%q; re-encoded output mismatch; expected `%s`, got `%s`", gold.path, want, got)
+		}
+		if n := dec.nodeWithName("B14"); n.LoopHead != dec.nodeWithName("B13") {
+			t.Errorf("%q; loop header of %q not decoded by reference", gold.path, n.name)
+		}
+	}
+}
+
 func TestMerge(t *testing.T) {
 	golden := []struct {
 		path     string
@@ -108,6 +516,253 @@ func TestMerge(t *testing.T) {
 	}
 }
 
+func TestCollapse(t *testing.T) {
+	golden := []struct {
+		path     string
+		wantPath string
+		nodes    map[string]bool
+		id       string
+	}{
+		{
+			path:     "testdata/sample.dot",
+			wantPath: "testdata/sample.dot.I1.golden",
+			nodes:    map[string]bool{"B1": true, "B2": true, "B3": true, "B4": true, "B5": true},
+			id:       "I1",
+		},
+	}
+	for _, gold := range golden {
+		// Parse input.
+		g, err := ParseFile(gold.path)
+		if err != nil {
+			t.Errorf("%q; unable to parse file; %v", gold.path, err)
+			continue
+		}
+		// Parse golden output.
+		buf, err := ioutil.ReadFile(gold.wantPath)
+		if err != nil {
+			t.Errorf("%q; unable to parse file; %v", gold.path, err)
+			continue
+		}
+		want := strings.TrimSpace(string(buf))
+		// Collapse in place.
+		n := g.Collapse(gold.nodes, gold.id)
+		if n.name != gold.id {
+			t.Errorf("%q; node name mismatch; expected %q, got %q", gold.path, gold.id, n.name)
+		}
+		got := g.String()
+		if got != want {
+			t.Errorf("%q; output mismatch; expected `%s`, got `%s`", gold.path, want, got)
+			continue
+		}
+	}
+}
+
+func TestCollapseIDs(t *testing.T) {
+	g, err := ParseFile("testdata/sample.dot")
+	if err != nil {
+		t.Fatalf("unable to parse file; %v", err)
+	}
+	var last *Node
+	for nodes := g.Nodes(); nodes.Next(); {
+		if n := node(nodes.Node()); last == nil || n.ID() > last.ID() {
+			last = n
+		}
+	}
+	// IDs of removed nodes are never reused.
+	g.RemoveNode(last)
+	n := g.Collapse(map[string]bool{"B1": true, "B2": true}, "I1")
+	if n.ID() <= last.ID() {
+		t.Errorf("node ID of removed node %q reused; expected ID greater than %d, got %d", last.name, last.ID(), n.ID())
+	}
+	// IDs of nodes removed from the source graph are not reused by clones.
+	m := g.Clone().Collapse(map[string]bool{"I1": true, "B3": true}, "I2")
+	if m.ID() <= n.ID() {
+		t.Errorf("node ID of removed node %q reused; expected ID greater than %d, got %d", n.name, n.ID(), m.ID())
+	}
+}
+
+func TestClone(t *testing.T) {
+	golden := []struct {
+		path string
+	}{
+		{path: "testdata/a.dot"},
+		{path: "testdata/sample.dot"},
+	}
+	for _, gold := range golden {
+		g, err := ParseFile(gold.path)
+		if err != nil {
+			t.Errorf("%q; unable to parse file; %v", gold.path, err)
+			continue
+		}
+		want := g.String()
+		dup := g.Clone()
+		if got := dup.String(); got != want {
+			t.Errorf("%q; output mismatch; expected `%s`, got `%s`", gold.path, want, got)
+			continue
+		}
+		// Edit the nodes and edges of the clone.
+		for _, n := range graph.NodesOf(dup.Nodes()) {
+			if g.Node(n.ID()) == n {
+				t.Errorf("%q; node %q shared between graph and clone", gold.path, node(n).name)
+			}
+			node(n).Attrs["color"] = "red"
+			for _, succ := range graph.NodesOf(dup.From(n.ID())) {
+				edge(dup.Edge(n.ID(), succ.ID())).Attrs["style"] = "dashed"
+			}
+		}
+		if got := g.String(); got != want {
+			t.Errorf("%q; original graph modified through clone; expected `%s`, got `%s`", gold.path, want, got)
+		}
+	}
+}
+
+func TestOrigins(t *testing.T) {
+	golden := []struct {
+		path string
+		// Nodes merged at each level.
+		merges []map[string]bool
+		want   []string
+	}{
+		{
+			path: "testdata/sample.dot",
+			merges: []map[string]bool{
+				{"B1": true, "B2": true, "B3": true, "B4": true, "B5": true},
+				{"B13": true, "B14": true},
+				{"I1": true, "B6": true, "I2": true},
+			},
+			want: []string{"B1", "B2", "B3", "B4", "B5", "B6", "B13", "B14"},
+		},
+	}
+	for _, gold := range golden {
+		g, err := ParseFile(gold.path)
+		if err != nil {
+			t.Errorf("%q; unable to parse file; %v", gold.path, err)
+			continue
+		}
+		var name string
+		for i, nodes := range gold.merges {
+			name = fmt.Sprintf("I%d", i+1)
+			g = Merge(g, nodes, name)
+		}
+		var got []string
+		for _, n := range g.nodeWithName(name).Origins() {
+			got = append(got, n.name)
+		}
+		if !reflect.DeepEqual(got, gold.want) {
+			t.Errorf("%q; original nodes mismatch; expected `%v`, got `%v`", gold.path, gold.want, got)
+		}
+	}
+}
+
+func TestSplitCriticalEdges(t *testing.T) {
+	golden := []struct {
+		path     string
+		wantPath string
+		// Names of the new nodes.
+		want []string
+	}{
+		{
+			path:     "testdata/critical.dot",
+			wantPath: "testdata/critical.dot.split.golden",
+			want:     []string{"A_B", "A_C", "C_E", "E_B"},
+		},
+	}
+	for _, gold := range golden {
+		// Parse input.
+		in, err := ParseFile(gold.path)
+		if err != nil {
+			t.Errorf("%q; unable to parse file; %v", gold.path, err)
+			continue
+		}
+		// Parse golden output.
+		buf, err := ioutil.ReadFile(gold.wantPath)
+		if err != nil {
+			t.Errorf("%q; unable to parse file; %v", gold.path, err)
+			continue
+		}
+		want := strings.TrimSpace(string(buf))
+		// Split critical edges.
+		var names []string
+		for _, n := range SplitCriticalEdges(in) {
+			names = append(names, n.name)
+		}
+		if !reflect.DeepEqual(names, gold.want) {
+			t.Errorf("%q; new nodes mismatch; expected `%v`, got `%v`", gold.path, gold.want, names)
+		}
+		got := in.String()
+		if got != want {
+			t.Errorf("%q; output mismatch; expected `%s`, got `%s`", gold.path, want, got)
+			continue
+		}
+	}
+}
+
+func TestSimplify(t *testing.T) {
+	golden := []struct {
+		path     string
+		wantPath string
+		// Mapping from old to new node names.
+		want map[string]string
+	}{
+		{
+			path:     "testdata/simplify.dot",
+			wantPath: "testdata/simplify.dot.golden",
+			want: map[string]string{
+				"A":  "A",
+				"B":  "B",
+				"C":  "C",
+				"D":  "C",
+				"E":  "C",
+				"F":  "F",
+				"G":  "F",
+				"J1": "C",
+				"J2": "F",
+			},
+		},
+	}
+	// Nodes with names starting with "J" contain only a jump.
+	forwarding := func(n *Node) bool {
+		return strings.HasPrefix(n.name, "J")
+	}
+	for _, gold := range golden {
+		// Parse input.
+		in, err := ParseFile(gold.path)
+		if err != nil {
+			t.Errorf("%q; unable to parse file; %v", gold.path, err)
+			continue
+		}
+		// Parse golden output.
+		buf, err := ioutil.ReadFile(gold.wantPath)
+		if err != nil {
+			t.Errorf("%q; unable to parse file; %v", gold.path, err)
+			continue
+		}
+		want := strings.TrimSpace(string(buf))
+		// Simplify.
+		before := in.String()
+		out, m := Simplify(in, forwarding)
+		if got := in.String(); got != before {
+			t.Errorf("%q; input graph modified; expected `%s`, got `%s`", gold.path, before, got)
+		}
+		names := make(map[string]string)
+		for old, n := range m {
+			names[old.name] = n.name
+			// The simplified graph shares no nodes with the input graph.
+			if n == old || out.Node(n.ID()) != graph.Node(n) {
+				t.Errorf("%q; invalid mapping of node %q; not a node of the simplified graph", gold.path, old.name)
+			}
+		}
+		if !reflect.DeepEqual(names, gold.want) {
+			t.Errorf("%q; node mapping mismatch; expected `%v`, got `%v`", gold.path, gold.want, names)
+		}
+		got := out.String()
+		if got != want {
+			t.Errorf("%q; output mismatch; expected `%s`, got `%s`", gold.path, want, got)
+			continue
+		}
+	}
+}
+
 func TestInitDFSOrder(t *testing.T) {
 	golden := []struct {
 		path string
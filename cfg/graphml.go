@@ -0,0 +1,265 @@
+package cfg
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"sort"
+	"strconv"
+
+	"github.com/pkg/errors"
+	"gonum.org/v1/gonum/graph"
+)
+
+// graphmlNamespace is the XML namespace of GraphML documents.
+const graphmlNamespace = "http://graphml.graphdrawing.org/xmlns"
+
+// graphmlEntryKey is the key ID of the entry flag of nodes in GraphML
+// documents.
+const graphmlEntryKey = "entry"
+
+// graphmlDoc is a GraphML document.
+type graphmlDoc struct {
+	XMLName xml.Name        `xml:"graphml"`
+	Xmlns   string          `xml:"xmlns,attr,omitempty"`
+	Keys    []*graphmlKey   `xml:"key"`
+	Graphs  []*graphmlGraph `xml:"graph"`
+}
+
+// graphmlKey is a GraphML key, declaring an attribute of nodes or edges.
+type graphmlKey struct {
+	ID   string `xml:"id,attr"`
+	For  string `xml:"for,attr"`
+	Name string `xml:"attr.name,attr,omitempty"`
+	Type string `xml:"attr.type,attr,omitempty"`
+}
+
+// graphmlGraph is a GraphML graph.
+type graphmlGraph struct {
+	ID          string         `xml:"id,attr,omitempty"`
+	EdgeDefault string         `xml:"edgedefault,attr"`
+	Nodes       []*graphmlNode `xml:"node"`
+	Edges       []*graphmlEdge `xml:"edge"`
+}
+
+// graphmlNode is a GraphML node.
+type graphmlNode struct {
+	ID   string         `xml:"id,attr"`
+	Data []*graphmlData `xml:"data"`
+}
+
+// graphmlEdge is a GraphML edge.
+type graphmlEdge struct {
+	Source string         `xml:"source,attr"`
+	Target string         `xml:"target,attr"`
+	Data   []*graphmlData `xml:"data"`
+}
+
+// graphmlData is the value of a GraphML attribute.
+type graphmlData struct {
+	Key   string `xml:"key,attr"`
+	Value string `xml:",chardata"`
+}
+
+// GraphML returns the string representation of the graph in GraphML format.
+//
+// Node names are stored as node IDs. The entry node is recorded by the boolean
+// "entry" attribute of nodes, and DOT attributes of nodes and edges (e.g. edge
+// labels) are recorded as string attributes of the same name.
+func (g *Graph) GraphML() string {
+	doc := &graphmlDoc{
+		Xmlns: graphmlNamespace,
+		Keys: []*graphmlKey{
+			{ID: graphmlEntryKey, For: "node", Name: "entry", Type: "boolean"},
+		},
+	}
+	gg := &graphmlGraph{
+		ID:          g.id,
+		EdgeDefault: "directed",
+	}
+	doc.Graphs = append(doc.Graphs, gg)
+	nodeKeys := make(map[string]bool)
+	edgeKeys := make(map[string]bool)
+	nodes := sortByID(graph.NodesOf(g.Nodes()))
+	for _, n := range nodes {
+		gn := &graphmlNode{ID: n.name}
+		if n.entry {
+			gn.Data = append(gn.Data, &graphmlData{Key: graphmlEntryKey, Value: "true"})
+		}
+		for _, key := range sortedKeys(n.Attrs) {
+			val := n.Attrs[key]
+			// The entry node is recorded by the entry attribute, not by its DOT
+			// label.
+			if n.entry && key == "label" && val == "entry" {
+				continue
+			}
+			nodeKeys[key] = true
+			gn.Data = append(gn.Data, &graphmlData{Key: graphmlNodeKey(key), Value: val})
+		}
+		gg.Nodes = append(gg.Nodes, gn)
+	}
+	for _, from := range nodes {
+		for _, to := range sortByID(graph.NodesOf(g.From(from.ID()))) {
+			e := edge(g.Edge(from.ID(), to.ID()))
+			ge := &graphmlEdge{Source: from.name, Target: to.name}
+			for _, key := range sortedKeys(e.Attrs) {
+				edgeKeys[key] = true
+				ge.Data = append(ge.Data, &graphmlData{Key: graphmlEdgeKey(key), Value: e.Attrs[key]})
+			}
+			gg.Edges = append(gg.Edges, ge)
+		}
+	}
+	for _, key := range sortedSet(nodeKeys) {
+		doc.Keys = append(doc.Keys, &graphmlKey{ID: graphmlNodeKey(key), For: "node", Name: key, Type: "string"})
+	}
+	for _, key := range sortedSet(edgeKeys) {
+		doc.Keys = append(doc.Keys, &graphmlKey{ID: graphmlEdgeKey(key), For: "edge", Name: key, Type: "string"})
+	}
+	buf, err := xml.MarshalIndent(doc, "", "\t")
+	if err != nil {
+		panic(fmt.Errorf("unable to marshal control flow graph in GraphML format; %v", err))
+	}
+	return xml.Header + string(buf)
+}
+
+// ParseGraphML parses the given GraphML file into a control flow graph, reading
+// from r.
+func ParseGraphML(r io.Reader) (*Graph, error) {
+	buf, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	return ParseGraphMLBytes(buf)
+}
+
+// ParseGraphMLFile parses the given GraphML file into a control flow graph,
+// reading from path.
+func ParseGraphMLFile(path string) (*Graph, error) {
+	buf, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	return ParseGraphMLBytes(buf)
+}
+
+// ParseGraphMLBytes parses the given GraphML file into a control flow graph,
+// reading from b.
+//
+// Attributes of nodes and edges are recorded as DOT attributes by attribute
+// name, and the entry node is identified by the boolean "entry" attribute.
+// Keys without attribute name (e.g. graphics of yEd) are ignored. Only the
+// first graph of the document is parsed.
+func ParseGraphMLBytes(b []byte) (*Graph, error) {
+	var doc graphmlDoc
+	if err := xml.Unmarshal(b, &doc); err != nil {
+		return nil, errors.WithStack(err)
+	}
+	if len(doc.Graphs) == 0 {
+		return nil, errors.New("unable to locate graph in GraphML document")
+	}
+	gg := doc.Graphs[0]
+	if gg.EdgeDefault == "undirected" {
+		return nil, errors.New("support for undirected GraphML graphs not yet implemented")
+	}
+	// keys maps from key ID to key.
+	keys := make(map[string]*graphmlKey)
+	for _, key := range doc.Keys {
+		keys[key.ID] = key
+	}
+	g := NewGraph()
+	g.id = gg.ID
+	for _, gn := range gg.Nodes {
+		if len(gn.ID) == 0 {
+			return nil, errors.New("invalid node; empty node ID")
+		}
+		if _, ok := g.NodeWithName(gn.ID); ok {
+			return nil, errors.Errorf("invalid node; node ID %q already present in graph", gn.ID)
+		}
+		n := g.NewNodeWithName(gn.ID)
+		for _, data := range gn.Data {
+			key, ok := keys[data.Key]
+			if !ok {
+				return nil, errors.Errorf("unable to locate key %q of node %q", data.Key, gn.ID)
+			}
+			switch {
+			case key.For != "node" && key.For != "all", len(key.Name) == 0:
+				// nothing to do.
+			case key.ID == graphmlEntryKey || (key.Name == "entry" && key.Type == "boolean"):
+				entry, err := strconv.ParseBool(data.Value)
+				if err != nil {
+					return nil, errors.Wrapf(err, "invalid entry attribute of node %q", gn.ID)
+				}
+				n.entry = entry
+			default:
+				n.Attrs[key.Name] = data.Value
+			}
+		}
+		if n.entry && g.entry != nil {
+			return nil, errors.Errorf("ambiguous entry node; nodes %q and %q flagged as entry", node(g.entry).name, n.name)
+		}
+		g.AddNode(n)
+	}
+	for _, ge := range gg.Edges {
+		from, ok := g.NodeWithName(ge.Source)
+		if !ok {
+			return nil, errors.Errorf("unable to locate source node %q of edge", ge.Source)
+		}
+		to, ok := g.NodeWithName(ge.Target)
+		if !ok {
+			return nil, errors.Errorf("unable to locate target node %q of edge", ge.Target)
+		}
+		e := edge(g.NewEdge(from, to))
+		for _, data := range ge.Data {
+			key, ok := keys[data.Key]
+			if !ok {
+				return nil, errors.Errorf("unable to locate key %q of edge (%q -> %q)", data.Key, ge.Source, ge.Target)
+			}
+			if (key.For != "edge" && key.For != "all") || len(key.Name) == 0 {
+				continue
+			}
+			e.Attrs[key.Name] = data.Value
+		}
+		g.SetEdge(e)
+	}
+	if g.entry == nil {
+		return nil, errors.New("unable to locate entry node in GraphML document")
+	}
+	return g, nil
+}
+
+// ParseGraphMLString parses the given GraphML file into a control flow graph,
+// reading from s.
+func ParseGraphMLString(s string) (*Graph, error) {
+	return ParseGraphMLBytes([]byte(s))
+}
+
+// graphmlNodeKey returns the key ID of the given DOT attribute of nodes.
+func graphmlNodeKey(name string) string {
+	return "n_" + name
+}
+
+// graphmlEdgeKey returns the key ID of the given DOT attribute of edges.
+func graphmlEdgeKey(name string) string {
+	return "e_" + name
+}
+
+// sortedKeys returns the keys of the given attributes in sorted order.
+func sortedKeys(attrs Attrs) []string {
+	var keys []string
+	for key := range attrs {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// sortedSet returns the members of the given set in sorted order.
+func sortedSet(set map[string]bool) []string {
+	var members []string
+	for member := range set {
+		members = append(members, member)
+	}
+	sort.Strings(members)
+	return members
+}
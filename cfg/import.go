@@ -0,0 +1,25 @@
+package cfg
+
+// Control flow graphs imported from compiler and disassembler dumps are
+// normalized to the form of NewGraphFromFunc; the entry node is set, 2-way
+// conditionals have edges labelled "true" and "false", and n-way conditionals
+// have edges labelled by case (e.g. "case (x=1)" and "default case").
+
+// condEdges adds the edges of a 2-way conditional from the given node to its
+// true and false targets; or an unconditional edge if both targets are the
+// same.
+func condEdges(g *Graph, from, t, f *Node) {
+	if t == f {
+		edgeWithLabel(g, from, t, "")
+		return
+	}
+	edgeWithLabel(g, from, t, "true")
+	edgeWithLabel(g, from, f, "false")
+}
+
+// caseEdge adds the edge of a case of an n-way conditional from the given node
+// to the target node, with the given case label; e.g. "case (x=1)" or "default
+// case". Cases sharing the same target are recorded on a single edge.
+func caseEdge(g *Graph, from, to *Node, label string) {
+	edgeWithLabel(g, from, to, caseLabel(g, from, to, label))
+}
@@ -0,0 +1,408 @@
+package cfg
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/graphism/exp/logic"
+	"github.com/graphism/simple"
+	"github.com/pkg/errors"
+	"gonum.org/v1/gonum/graph"
+)
+
+// JSONVersion is the version of the JSON encoding of control flow graphs.
+const JSONVersion = 1
+
+// Edge kinds of the JSON encoding, based on the edge label.
+const (
+	// Unconditional edge.
+	edgeKindUncond = "unconditional"
+	// True branch of a 2-way conditional.
+	edgeKindTrue = "true"
+	// False branch of a 2-way conditional.
+	edgeKindFalse = "false"
+	// Case of an n-way conditional.
+	edgeKindCase = "case"
+)
+
+// jsonGraph is the JSON encoding of a control flow graph.
+type jsonGraph struct {
+	// Version of the JSON encoding.
+	Version int `json:"version"`
+	// Graph ID.
+	ID string `json:"id,omitempty"`
+	// Name of the entry node.
+	Entry string `json:"entry,omitempty"`
+	// Nodes in order of node ID.
+	Nodes []*jsonNode `json:"nodes"`
+	// Edges in order of source and destination node ID.
+	Edges []*jsonEdge `json:"edges"`
+}
+
+// jsonNode is the JSON encoding of a control flow graph node. Nodes are
+// referenced by name.
+type jsonNode struct {
+	Name         string          `json:"name"`
+	Attrs        Attrs           `json:"attrs,omitempty"`
+	Pre          int             `json:"pre"`
+	RevPost      int             `json:"rev_post"`
+	NBackEdges   int             `json:"nbackedges,omitempty"`
+	IsLatch      bool            `json:"is_latch,omitempty"`
+	LoopType     LoopType        `json:"loop_type,omitempty"`
+	LoopHead     string          `json:"loop_head,omitempty"`
+	Latch        string          `json:"latch,omitempty"`
+	LoopFollow   string          `json:"loop_follow,omitempty"`
+	IfFollow     string          `json:"if_follow,omitempty"`
+	SwitchHead   string          `json:"switch_head,omitempty"`
+	SwitchFollow string          `json:"switch_follow,omitempty"`
+	Cond         json.RawMessage `json:"cond,omitempty"`
+	CondNodes    []string        `json:"cond_nodes,omitempty"`
+	Origins      []string        `json:"origins,omitempty"`
+}
+
+// jsonEdge is the JSON encoding of a control flow graph edge.
+type jsonEdge struct {
+	From  string `json:"from"`
+	To    string `json:"to"`
+	Kind  string `json:"kind"`
+	Attrs Attrs  `json:"attrs,omitempty"`
+	// Case values of case edges.
+	Cases []string `json:"cases,omitempty"`
+	// Default case of case edges.
+	Default bool `json:"default,omitempty"`
+}
+
+// MarshalJSON returns the versioned JSON encoding of the control flow graph;
+// implements json.Marshaler.
+//
+// The encoding records the nodes and edges of the graph with their attributes,
+// the kind of each edge (unconditional, true, false or case), the entry node,
+// the depth first search order and the structuring annotations of each node.
+// Node references are stored by name. Compound conditions are stored as
+// expression trees; a symbol as a string, a constant as a boolean, a
+// comparison as an object with an "eq" key holding the symbol and value, and
+// negations, conjunctions and disjunctions as objects with a "not", "and" or
+// "or" key respectively.
+func (g *Graph) MarshalJSON() ([]byte, error) {
+	jg := &jsonGraph{
+		Version: JSONVersion,
+		ID:      g.id,
+		Nodes:   []*jsonNode{},
+		Edges:   []*jsonEdge{},
+	}
+	if g.entry != nil {
+		jg.Entry = node(g.entry).name
+	}
+	nodes := sortByID(graph.NodesOf(g.Nodes()))
+	for _, n := range nodes {
+		jn := &jsonNode{
+			Name:         n.name,
+			Pre:          n.Pre,
+			RevPost:      n.RevPost,
+			NBackEdges:   n.NBackEdges,
+			IsLatch:      n.IsLatch,
+			LoopType:     n.LoopType,
+			LoopHead:     nameOf(n.LoopHead),
+			Latch:        nameOf(n.Latch),
+			LoopFollow:   nameOf(n.LoopFollow),
+			IfFollow:     nameOf(n.IfFollow),
+			SwitchHead:   nameOf(n.SwitchHead),
+			SwitchFollow: nameOf(n.SwitchFollow),
+			CondNodes:    namesOf(n.CondNodes),
+			Origins:      namesOf(n.origins),
+		}
+		for key, val := range n.Attrs {
+			// The entry node is recorded by the graph, not by its DOT label.
+			if n.entry && key == "label" && val == "entry" {
+				continue
+			}
+			if jn.Attrs == nil {
+				jn.Attrs = make(Attrs)
+			}
+			jn.Attrs[key] = val
+		}
+		if n.Cond != nil {
+			cond, err := json.Marshal(encodeExpr(n.Cond))
+			if err != nil {
+				return nil, errors.WithStack(err)
+			}
+			jn.Cond = cond
+		}
+		jg.Nodes = append(jg.Nodes, jn)
+	}
+	for _, from := range nodes {
+		for _, to := range sortByID(graph.NodesOf(g.From(from.ID()))) {
+			e := edge(g.Edge(from.ID(), to.ID()))
+			je := &jsonEdge{
+				From: from.name,
+				To:   to.name,
+			}
+			if len(e.Attrs) > 0 {
+				je.Attrs = e.Attrs
+			}
+			switch label := e.Attrs["label"]; label {
+			case "":
+				je.Kind = edgeKindUncond
+			case "true":
+				je.Kind = edgeKindTrue
+			case "false":
+				je.Kind = edgeKindFalse
+			default:
+				values, isDefault, err := e.Cases()
+				if err != nil {
+					// Unknown edge label; e.g. edge of user-provided DOT file.
+					je.Kind = edgeKindUncond
+					break
+				}
+				je.Kind = edgeKindCase
+				je.Cases = values
+				je.Default = isDefault
+			}
+			jg.Edges = append(jg.Edges, je)
+		}
+	}
+	return json.Marshal(jg)
+}
+
+// UnmarshalJSON decodes the control flow graph from its versioned JSON
+// encoding, replacing the contents of g; implements json.Unmarshaler.
+//
+// Structuring annotations referencing nodes not present in the graph, and the
+// original nodes of CondNodes and Origins, are decoded as nodes outside of the
+// graph; shared by name.
+func (g *Graph) UnmarshalJSON(b []byte) error {
+	var jg jsonGraph
+	if err := json.Unmarshal(b, &jg); err != nil {
+		return errors.WithStack(err)
+	}
+	if jg.Version != JSONVersion {
+		return errors.Errorf("support for JSON encoding version %d of control flow graph not yet implemented; expected version %d", jg.Version, JSONVersion)
+	}
+	*g = *NewGraph()
+	g.id = jg.ID
+	for _, jn := range jg.Nodes {
+		if len(jn.Name) == 0 {
+			return errors.New("invalid node; empty node name")
+		}
+		if _, ok := g.NodeWithName(jn.Name); ok {
+			return errors.Errorf("invalid node; node name %q already present in graph", jn.Name)
+		}
+		n := g.NewNodeWithName(jn.Name)
+		for key, val := range jn.Attrs {
+			n.Attrs[key] = val
+		}
+		n.Pre = jn.Pre
+		n.RevPost = jn.RevPost
+		n.NBackEdges = jn.NBackEdges
+		n.IsLatch = jn.IsLatch
+		n.LoopType = jn.LoopType
+		g.AddNode(n)
+	}
+	if len(jg.Entry) > 0 {
+		entry, ok := g.NodeWithName(jg.Entry)
+		if !ok {
+			return errors.Errorf("unable to locate entry node %q", jg.Entry)
+		}
+		g.SetEntry(entry)
+	}
+	// Nodes outside of the graph are given IDs following the nodes of the graph.
+	next := g.newNodeAfter("").ID()
+	outside := make(map[string]*Node)
+	// original returns the node outside of the graph with the given name.
+	original := func(name string) *Node {
+		if n, ok := outside[name]; ok {
+			return n
+		}
+		n := &Node{
+			Node:  simple.Node(next),
+			name:  name,
+			Attrs: make(Attrs),
+		}
+		next++
+		outside[name] = n
+		return n
+	}
+	// ref returns the node with the given name; or nil if the name is empty.
+	ref := func(name string) *Node {
+		if len(name) == 0 {
+			return nil
+		}
+		if n, ok := g.NodeWithName(name); ok {
+			return n
+		}
+		return original(name)
+	}
+	for _, jn := range jg.Nodes {
+		n := g.nodeWithName(jn.Name)
+		n.LoopHead = ref(jn.LoopHead)
+		n.Latch = ref(jn.Latch)
+		n.LoopFollow = ref(jn.LoopFollow)
+		n.IfFollow = ref(jn.IfFollow)
+		n.SwitchHead = ref(jn.SwitchHead)
+		n.SwitchFollow = ref(jn.SwitchFollow)
+		for _, name := range jn.CondNodes {
+			n.CondNodes = append(n.CondNodes, original(name))
+		}
+		for _, name := range jn.Origins {
+			n.origins = append(n.origins, original(name))
+		}
+		if len(jn.Cond) > 0 {
+			var v interface{}
+			if err := json.Unmarshal(jn.Cond, &v); err != nil {
+				return errors.WithStack(err)
+			}
+			cond, err := decodeExpr(v)
+			if err != nil {
+				return errors.Wrapf(err, "unable to decode condition of node %q", jn.Name)
+			}
+			n.Cond = cond
+		}
+	}
+	for _, je := range jg.Edges {
+		from, ok := g.NodeWithName(je.From)
+		if !ok {
+			return errors.Errorf("unable to locate source node %q of edge", je.From)
+		}
+		to, ok := g.NodeWithName(je.To)
+		if !ok {
+			return errors.Errorf("unable to locate destination node %q of edge", je.To)
+		}
+		if g.HasEdgeFromTo(from.ID(), to.ID()) {
+			return errors.Errorf("invalid edge (%q -> %q); edge already present in graph", je.From, je.To)
+		}
+		e := edge(g.NewEdge(from, to))
+		for key, val := range je.Attrs {
+			e.Attrs[key] = val
+		}
+		// Edges of 2-way conditionals are identified by edge label.
+		switch je.Kind {
+		case edgeKindUncond, edgeKindCase:
+			// nothing to do.
+		case edgeKindTrue, edgeKindFalse:
+			if _, ok := e.Attrs["label"]; !ok {
+				e.Attrs["label"] = je.Kind
+			}
+		default:
+			return errors.Errorf("support for edge kind %q of edge (%q -> %q) not yet implemented", je.Kind, je.From, je.To)
+		}
+		g.SetEdge(e)
+	}
+	return nil
+}
+
+// encodeExpr returns the JSON value of the given boolean expression.
+func encodeExpr(x logic.Expr) interface{} {
+	switch x := x.(type) {
+	case logic.Const:
+		return bool(x)
+	case logic.Sym:
+		return string(x)
+	case logic.Eq:
+		return map[string]interface{}{"eq": []interface{}{string(x.X), x.Value}}
+	case *logic.Not:
+		return map[string]interface{}{"not": encodeExpr(x.X)}
+	case *logic.And:
+		return map[string]interface{}{"and": encodeExprs(x.Xs)}
+	case *logic.Or:
+		return map[string]interface{}{"or": encodeExprs(x.Xs)}
+	default:
+		panic(fmt.Errorf("support for boolean expression %T not yet implemented", x))
+	}
+}
+
+// encodeExprs returns the JSON values of the given boolean expressions.
+func encodeExprs(xs []logic.Expr) []interface{} {
+	vs := make([]interface{}, 0, len(xs))
+	for _, x := range xs {
+		vs = append(vs, encodeExpr(x))
+	}
+	return vs
+}
+
+// decodeExpr returns the boolean expression of the given JSON value.
+func decodeExpr(v interface{}) (logic.Expr, error) {
+	switch v := v.(type) {
+	case bool:
+		return logic.Const(v), nil
+	case string:
+		return logic.Sym(v), nil
+	case map[string]interface{}:
+		if len(v) != 1 {
+			return nil, errors.Errorf("invalid number of keys in boolean expression object; expected 1, got %d", len(v))
+		}
+		if x, ok := v["not"]; ok {
+			y, err := decodeExpr(x)
+			if err != nil {
+				return nil, errors.WithStack(err)
+			}
+			return &logic.Not{X: y}, nil
+		}
+		if x, ok := v["eq"]; ok {
+			operands, ok := x.([]interface{})
+			if !ok || len(operands) != 2 {
+				return nil, errors.Errorf("invalid operands of comparison; expected JSON array of symbol and value, got %v", x)
+			}
+			sym, ok := operands[0].(string)
+			if !ok {
+				return nil, errors.Errorf("invalid symbol of comparison; expected JSON string, got %T", operands[0])
+			}
+			value, ok := operands[1].(string)
+			if !ok {
+				return nil, errors.Errorf("invalid value of comparison; expected JSON string, got %T", operands[1])
+			}
+			return logic.Eq{X: logic.Sym(sym), Value: value}, nil
+		}
+		if xs, ok := v["and"]; ok {
+			ys, err := decodeExprs(xs)
+			if err != nil {
+				return nil, errors.WithStack(err)
+			}
+			return &logic.And{Xs: ys}, nil
+		}
+		if xs, ok := v["or"]; ok {
+			ys, err := decodeExprs(xs)
+			if err != nil {
+				return nil, errors.WithStack(err)
+			}
+			return &logic.Or{Xs: ys}, nil
+		}
+		for key := range v {
+			return nil, errors.Errorf("support for boolean operator %q not yet implemented", key)
+		}
+	}
+	return nil, errors.Errorf("support for boolean expression of JSON type %T not yet implemented", v)
+}
+
+// decodeExprs returns the boolean expressions of the given JSON array.
+func decodeExprs(v interface{}) ([]logic.Expr, error) {
+	vs, ok := v.([]interface{})
+	if !ok {
+		return nil, errors.Errorf("invalid operands of boolean expression; expected JSON array, got %T", v)
+	}
+	var xs []logic.Expr
+	for _, v := range vs {
+		x, err := decodeExpr(v)
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+		xs = append(xs, x)
+	}
+	return xs, nil
+}
+
+// nameOf returns the name of the given node; or the empty string if nil.
+func nameOf(n *Node) string {
+	if n == nil {
+		return ""
+	}
+	return n.name
+}
+
+// namesOf returns the names of the given nodes.
+func namesOf(ns []*Node) []string {
+	var names []string
+	for _, n := range ns {
+		names = append(names, n.name)
+	}
+	return names
+}
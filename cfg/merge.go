@@ -1,57 +1,83 @@
 package cfg
 
 import (
+	"github.com/graphism/simple"
 	"gonum.org/v1/gonum/graph"
 )
 
 // Merge returns a new control flow graph where the specified nodes have been
 // collapsed into a single node with the new node name, and the predecessors and
-// successors of the specified nodes.
+// successors of the specified nodes. The new node represents the original nodes
+// of the specified nodes, as recorded by Origins.
+//
+// Merge copies the source graph; use Collapse to merge nodes in place.
 func Merge(src *Graph, delNodes map[string]bool, newName string) *Graph {
 	dst := NewGraph()
 	Copy(dst, src)
+	dst.Collapse(delNodes, newName)
+	return dst
+}
+
+// Collapse collapses the specified nodes of g in place into a single node with
+// the new node name, and the predecessors and successors of the specified
+// nodes, and returns the new node. The new node represents the original nodes
+// of the specified nodes, as recorded by Origins.
+func (g *Graph) Collapse(delNodes map[string]bool, newName string) *Node {
 	// preds marks predecessor nodes and records their edge attributes.
 	preds := make(map[graph.Node]Attrs)
 	succs := make(map[graph.Node]bool)
-	newNode := dst.NewNodeWithName(newName)
+	newNode := g.newNodeAfter(newName)
 	for delName := range delNodes {
-		delNode := dst.nodeWithName(delName)
+		delNode := g.nodeWithName(delName)
 		if delNode.entry {
 			newNode.entry = true
 		}
+		newNode.origins = append(newNode.origins, delNode.Origins()...)
 		// Record predecessors not part of nodes.
-		predNodes := dst.To(delNode.ID())
+		predNodes := g.To(delNode.ID())
 		for predNodes.Next() {
 			pred := predNodes.Node()
 			p := node(pred)
 			if !delNodes[p.name] {
-				preds[dst.nodeWithName(p.name)] = edge(dst.Edge(p.ID(), delNode.ID())).Attrs
+				preds[g.nodeWithName(p.name)] = edge(g.Edge(p.ID(), delNode.ID())).Attrs
 			}
 		}
 		// Record successors not part of nodes.
-		succNodes := dst.From(delNode.ID())
+		succNodes := g.From(delNode.ID())
 		for succNodes.Next() {
 			succ := succNodes.Node()
 			s := node(succ)
 			if !delNodes[s.name] {
-				succs[dst.nodeWithName(s.name)] = true
+				succs[g.nodeWithName(s.name)] = true
 			}
 		}
-		dst.RemoveNode(delNode)
+		g.RemoveNode(delNode)
 	}
+	sortByName(newNode.origins)
 	// Add new node after removing old nodes, to prevent potential collision with
 	// previous entry node.
-	dst.AddNode(newNode)
+	g.AddNode(newNode)
 	// Add edges from predecessors to new node.
 	for pred, attrs := range preds {
-		e := edge(dst.NewEdge(pred, newNode))
+		e := edge(g.NewEdge(pred, newNode))
 		e.Attrs = attrs
-		dst.SetEdge(e)
+		g.SetEdge(e)
 	}
 	// Add edges from new node to successors.
 	for succ := range succs {
-		e := dst.NewEdge(newNode, succ)
-		dst.SetEdge(e)
+		e := g.NewEdge(newNode, succ)
+		g.SetEdge(e)
+	}
+	return newNode
+}
+
+// newNodeAfter returns a new node with the given name, and an ID greater than
+// the IDs of the nodes added to g. Unlike NewNode, IDs of removed nodes are
+// never reused; thus node order is independent of prior removals.
+func (g *Graph) newNodeAfter(name string) *Node {
+	return &Node{
+		Node:  simple.Node(g.nextID),
+		name:  name,
+		Attrs: make(Attrs),
 	}
-	return dst
 }
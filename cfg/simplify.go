@@ -0,0 +1,178 @@
+package cfg
+
+import (
+	"gonum.org/v1/gonum/graph"
+)
+
+// Simplify returns a simplified copy of the control flow graph g, and a mapping
+// from the nodes of g to the nodes of the simplified graph. The simplified graph
+// shares no nodes or edges with g, as created by DeepCopy.
+//
+// Forwarding nodes, as reported by forwarding (e.g. basic blocks containing
+// only an unconditional jump), are removed by redirecting their incoming edges
+// to their unique successor. The attributes of redirected edges are kept, and
+// forwarding nodes are kept if a predecessor already has an edge to the
+// successor. The entry node is never removed. A nil forwarding function
+// removes no nodes.
+//
+// Straight-line chains of nodes, where each node has a unique successor which
+// in turn has a unique predecessor, are collapsed into a single node using
+// Collapse; named after the first node of the chain. The outgoing edges of the
+// last node of the chain keep their attributes.
+func Simplify(g *Graph, forwarding func(n *Node) bool) (*Graph, map[*Node]*Node) {
+	dst := NewGraph()
+	m := DeepCopy(dst, g)
+	// replace updates the nodes mapped to from to instead map to to.
+	replace := func(from, to *Node) {
+		for k, v := range m {
+			if v == from {
+				m[k] = to
+			}
+		}
+	}
+	// Remove forwarding nodes.
+	if forwarding != nil {
+		for _, n := range sortByDOTID(graph.NodesOf(dst.Nodes())) {
+			nn := node(n)
+			if !forwarding(nn) {
+				continue
+			}
+			if succ, ok := dst.removeForwarding(nn); ok {
+				replace(nn, succ)
+			}
+		}
+	}
+	// Collapse straight-line chains.
+	for {
+		chain := dst.findChain()
+		if chain == nil {
+			break
+		}
+		head, tail := chain[0], chain[len(chain)-1]
+		// Record outgoing edges of the tail, before merge.
+		succs := make(map[*Node]Attrs)
+		for _, succ := range graph.NodesOf(dst.From(tail.ID())) {
+			succs[node(succ)] = edge(dst.Edge(tail.ID(), succ.ID())).Attrs
+		}
+		delNodes := make(map[string]bool)
+		for _, n := range chain {
+			delNodes[n.name] = true
+		}
+		newNode := dst.Collapse(delNodes, head.name)
+		// Restore attributes of outgoing edges.
+		for succ, attrs := range succs {
+			to := succ
+			if succ == head {
+				// Back edge from the tail to the head of the chain.
+				to = newNode
+			}
+			e := edge(dst.NewEdge(newNode, to))
+			e.Attrs = copyAttrs(attrs)
+			dst.SetEdge(e)
+		}
+		for _, n := range chain {
+			replace(n, newNode)
+		}
+	}
+	return dst, m
+}
+
+// removeForwarding removes the forwarding node n from g by redirecting its
+// incoming edges to its unique successor. The successor is returned, and a
+// boolean variable indicating success.
+func (g *Graph) removeForwarding(n *Node) (*Node, bool) {
+	if n.entry {
+		return nil, false
+	}
+	succs := graph.NodesOf(g.From(n.ID()))
+	if len(succs) != 1 || succs[0].ID() == n.ID() {
+		return nil, false
+	}
+	succ := node(succs[0])
+	preds := graph.NodesOf(g.To(n.ID()))
+	for _, pred := range preds {
+		if g.HasEdgeFromTo(pred.ID(), succ.ID()) {
+			// Redirecting the edge would merge it with an existing edge of a
+			// different kind.
+			return nil, false
+		}
+	}
+	for _, pred := range sortByDOTID(preds) {
+		e := edge(g.NewEdge(pred, succ))
+		e.Attrs = copyAttrs(edge(g.Edge(pred.ID(), n.ID())).Attrs)
+		g.SetEdge(e)
+	}
+	g.RemoveNode(n)
+	return succ, true
+}
+
+// findChain returns the first straight-line chain of nodes in g, or nil if not
+// present.
+func (g *Graph) findChain() []*Node {
+	for _, n := range sortByDOTID(graph.NodesOf(g.Nodes())) {
+		succ, ok := g.chainSucc(node(n))
+		if !ok {
+			continue
+		}
+		// Locate the first node of the chain.
+		head := node(n)
+		seen := map[*Node]bool{head: true, succ: true}
+		for {
+			pred, ok := g.chainPred(head)
+			if !ok || seen[pred] {
+				break
+			}
+			seen[pred] = true
+			head = pred
+		}
+		chain := []*Node{head}
+		for in := map[*Node]bool{head: true}; ; {
+			succ, ok := g.chainSucc(chain[len(chain)-1])
+			if !ok || in[succ] {
+				break
+			}
+			in[succ] = true
+			chain = append(chain, succ)
+		}
+		return chain
+	}
+	return nil
+}
+
+// chainSucc returns the unique successor of n if it has n as unique
+// predecessor, and a boolean variable indicating success. The entry node is
+// never the successor of a chain.
+func (g *Graph) chainSucc(n *Node) (*Node, bool) {
+	succs := graph.NodesOf(g.From(n.ID()))
+	if len(succs) != 1 || succs[0].ID() == n.ID() {
+		return nil, false
+	}
+	succ := node(succs[0])
+	if succ.entry || g.To(succ.ID()).Len() != 1 {
+		return nil, false
+	}
+	return succ, true
+}
+
+// chainPred returns the unique predecessor of n if it has n as unique
+// successor, and a boolean variable indicating success.
+func (g *Graph) chainPred(n *Node) (*Node, bool) {
+	preds := graph.NodesOf(g.To(n.ID()))
+	if len(preds) != 1 {
+		return nil, false
+	}
+	pred := node(preds[0])
+	if succ, ok := g.chainSucc(pred); !ok || succ != n {
+		return nil, false
+	}
+	return pred, true
+}
+
+// copyAttrs returns a copy of the given attributes.
+func copyAttrs(attrs Attrs) Attrs {
+	dup := make(Attrs)
+	for key, val := range attrs {
+		dup[key] = val
+	}
+	return dup
+}
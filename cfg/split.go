@@ -0,0 +1,81 @@
+package cfg
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+
+	"gonum.org/v1/gonum/graph"
+)
+
+// SplitEdge subdivides the edge from -> to of g by inserting a new node, and
+// returns the new node. The new node is named "<from>_<to>", with a numeric
+// suffix added if the name is already present in the graph. The attributes of
+// the original edge (e.g. true, false or case labels) are moved to the split
+// edge from -> n, and the edge n -> to has no attributes.
+func SplitEdge(g *Graph, from, to graph.Node) *Node {
+	e := g.Edge(from.ID(), to.ID())
+	if e == nil {
+		panic(fmt.Errorf("unable to locate edge (%q -> %q)", node(from).DOTID(), node(to).DOTID()))
+	}
+	f, t := node(from), node(to)
+	base := fmt.Sprintf("%s_%s", unquote(f.name), unquote(t.name))
+	name := base
+	for i := 1; ; i++ {
+		if _, ok := g.NodeWithName(quote(name)); !ok {
+			break
+		}
+		name = fmt.Sprintf("%s_%d", base, i)
+	}
+	n := g.NewNodeWithName(quote(name))
+	g.AddNode(n)
+	g.RemoveEdge(f.ID(), t.ID())
+	split := edge(g.NewEdge(f, n))
+	split.Attrs = edge(e).Attrs
+	g.SetEdge(split)
+	g.SetEdge(g.NewEdge(n, t))
+	return n
+}
+
+// SplitCriticalEdges splits the critical edges of g, and returns the new nodes
+// in order of insertion. An edge is critical if its source node has several
+// successors and its destination node has several predecessors.
+func SplitCriticalEdges(g *Graph) []*Node {
+	var critical [][2]*Node
+	for _, from := range sortByDOTID(graph.NodesOf(g.Nodes())) {
+		succs := graph.NodesOf(g.From(from.ID()))
+		if len(succs) < 2 {
+			continue
+		}
+		for _, to := range sortByDOTID(succs) {
+			if g.To(to.ID()).Len() < 2 {
+				continue
+			}
+			critical = append(critical, [2]*Node{node(from), node(to)})
+		}
+	}
+	var nodes []*Node
+	for _, e := range critical {
+		nodes = append(nodes, SplitEdge(g, e[0], e[1]))
+	}
+	return nodes
+}
+
+// reID is a regular expression matching unquoted DOT IDs.
+var reID = regexp.MustCompile(`^([a-zA-Z_][a-zA-Z0-9_]*|-?([0-9]+(\.[0-9]*)?|\.[0-9]+))$`)
+
+// quote returns the given node name as a DOT ID, quoted if required.
+func quote(name string) string {
+	if reID.MatchString(name) {
+		return name
+	}
+	return strconv.Quote(name)
+}
+
+// unquote returns the given DOT ID as a node name, unquoted if quoted.
+func unquote(id string) string {
+	if s, err := strconv.Unquote(id); err == nil {
+		return s
+	}
+	return id
+}
@@ -108,3 +108,23 @@ func sortByDOTID(ns []graph.Node) []graph.Node {
 	sort.Slice(ns, less)
 	return ns
 }
+
+// sortByName sorts the given list of nodes by name.
+func sortByName(ns []*Node) {
+	less := func(i, j int) bool {
+		return natsort.Less(ns[i].name, ns[j].name)
+	}
+	sort.Slice(ns, less)
+}
+
+// sortByID sorts the given list of nodes by node ID.
+func sortByID(ns []graph.Node) []*Node {
+	sort.Slice(ns, func(i, j int) bool {
+		return ns[i].ID() < ns[j].ID()
+	})
+	var nodes []*Node
+	for _, n := range ns {
+		nodes = append(nodes, node(n))
+	}
+	return nodes
+}
@@ -0,0 +1,335 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"go/ast"
+	"go/token"
+	"io"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// printC prints the given function, as produced by genFunc, in C pseudo-code to
+// w.
+func printC(w io.Writer, f *ast.FuncDecl) error {
+	p := &cPrinter{
+		buf:    &bytes.Buffer{},
+		breaks: make(map[string]bool),
+		conts:  make(map[string]bool),
+	}
+	// Locate loops targeted by labelled break and continue statements, as these
+	// have no counterpart in C and are therefore translated into goto
+	// statements.
+	ast.Inspect(f.Body, func(n ast.Node) bool {
+		if stmt, ok := n.(*ast.BranchStmt); ok && stmt.Label != nil {
+			switch stmt.Tok {
+			case token.BREAK:
+				p.breaks[stmt.Label.Name] = true
+			case token.CONTINUE:
+				p.conts[stmt.Label.Name] = true
+			}
+		}
+		return true
+	})
+	fmt.Fprintf(p.buf, "void %s(void) {\n", f.Name.Name)
+	p.indent++
+	p.stmts(f.Body.List)
+	p.indent--
+	p.buf.WriteString("}\n")
+	if _, err := w.Write(p.buf.Bytes()); err != nil {
+		return errors.WithStack(err)
+	}
+	return nil
+}
+
+// cPrinter prints Go statements in C pseudo-code.
+type cPrinter struct {
+	buf *bytes.Buffer
+	// Current indentation level.
+	indent int
+	// Labels of loops targeted by labelled break statements.
+	breaks map[string]bool
+	// Labels of loops targeted by labelled continue statements.
+	conts map[string]bool
+}
+
+// line prints the given line at the current indentation level.
+func (p *cPrinter) line(format string, args ...interface{}) {
+	p.buf.WriteString(strings.Repeat("\t", p.indent))
+	fmt.Fprintf(p.buf, format, args...)
+	p.buf.WriteString("\n")
+}
+
+// label prints the given label followed by an empty statement, one
+// indentation level to the left of the current one.
+func (p *cPrinter) label(label string) {
+	p.indent--
+	p.line("%s:", label)
+	p.indent++
+	p.line(";")
+}
+
+// stmts prints the given list of statements.
+func (p *cPrinter) stmts(stmts []ast.Stmt) {
+	for _, stmt := range stmts {
+		p.stmt(stmt)
+	}
+}
+
+// stmt prints the given statement.
+func (p *cPrinter) stmt(stmt ast.Stmt) {
+	switch stmt := stmt.(type) {
+	case *ast.LabeledStmt:
+		if loop, ok := stmt.Stmt.(*ast.ForStmt); ok {
+			p.indent--
+			p.line("%s:", stmt.Label.Name)
+			p.indent++
+			p.loop(loop, stmt.Label.Name)
+			return
+		}
+		if _, ok := stmt.Stmt.(*ast.EmptyStmt); ok {
+			p.label(stmt.Label.Name)
+			return
+		}
+		p.indent--
+		p.line("%s:", stmt.Label.Name)
+		p.indent++
+		p.stmt(stmt.Stmt)
+	case *ast.EmptyStmt:
+		p.line(";")
+	case *ast.ReturnStmt:
+		p.line("return;")
+	case *ast.AssignStmt:
+		p.line("%s = %s;", cExpr(stmt.Lhs[0]), cExpr(stmt.Rhs[0]))
+	case *ast.BranchStmt:
+		switch {
+		case stmt.Tok == token.GOTO:
+			p.line("goto %s;", stmt.Label.Name)
+		case stmt.Tok == token.BREAK && stmt.Label != nil:
+			p.line("goto %s_break;", stmt.Label.Name)
+		case stmt.Tok == token.BREAK:
+			p.line("break;")
+		case stmt.Tok == token.CONTINUE && stmt.Label != nil:
+			p.line("goto %s_continue;", stmt.Label.Name)
+		case stmt.Tok == token.CONTINUE:
+			p.line("continue;")
+		default:
+			panic(fmt.Errorf("support for branch statement %v not yet implemented", stmt.Tok))
+		}
+	case *ast.BlockStmt:
+		p.line("{")
+		p.body(stmt.List)
+		p.line("}")
+	case *ast.IfStmt:
+		p.ifStmt(stmt, "")
+	case *ast.ForStmt:
+		p.loop(stmt, "")
+	case *ast.SwitchStmt:
+		p.switchStmt(stmt)
+	default:
+		panic(fmt.Errorf("support for statement %T not yet implemented", stmt))
+	}
+}
+
+// body prints the given list of statements at one indentation level to the
+// right of the current one.
+func (p *cPrinter) body(stmts []ast.Stmt) {
+	p.indent++
+	p.stmts(stmts)
+	p.indent--
+}
+
+// ifStmt prints the given if-statement, prefixed by prefix.
+func (p *cPrinter) ifStmt(stmt *ast.IfStmt, prefix string) {
+	p.line("%sif (%s) {", prefix, cExpr(stmt.Cond))
+	p.body(stmt.Body.List)
+	switch els := stmt.Else.(type) {
+	case nil:
+		p.line("}")
+	case *ast.IfStmt:
+		p.ifStmt(els, "} else ")
+	case *ast.BlockStmt:
+		p.line("} else {")
+		p.body(els.List)
+		p.line("}")
+	default:
+		panic(fmt.Errorf("support for else branch %T not yet implemented", els))
+	}
+}
+
+// loop prints the given loop, with the given label (if any). Endless loops
+// ending with a conditional break statement are printed as do-while loops,
+// unless the loop body contains continue statements targeting the loop.
+func (p *cPrinter) loop(stmt *ast.ForStmt, label string) {
+	body := stmt.Body.List
+	if cond, ok := doWhileCond(stmt, label); ok {
+		// do {} while (cond);
+		p.line("do {")
+		p.body(body[:len(body)-1])
+		p.line("} while (%s);", cExpr(cond))
+	} else {
+		if stmt.Cond != nil {
+			// while (cond) {}
+			p.line("while (%s) {", cExpr(stmt.Cond))
+		} else {
+			// for (;;) {}
+			p.line("for (;;) {")
+		}
+		p.body(body)
+		if p.conts[label] {
+			p.indent++
+			p.label(label + "_continue")
+			p.indent--
+		}
+		p.line("}")
+	}
+	if p.breaks[label] {
+		p.label(label + "_break")
+	}
+}
+
+// switchStmt prints the given switch statement.
+func (p *cPrinter) switchStmt(stmt *ast.SwitchStmt) {
+	p.line("switch (%s) {", cExpr(stmt.Tag))
+	for _, s := range stmt.Body.List {
+		clause, ok := s.(*ast.CaseClause)
+		if !ok {
+			panic(fmt.Errorf("invalid switch clause type; expected *ast.CaseClause, got %T", s))
+		}
+		if clause.List == nil {
+			p.line("default:")
+		}
+		for _, x := range clause.List {
+			p.line("case %s:", cExpr(x))
+		}
+		body := clause.Body
+		// Cases fall through by default in C.
+		fallsThrough := false
+		if l := len(body); l > 0 {
+			if branch, ok := body[l-1].(*ast.BranchStmt); ok && branch.Tok == token.FALLTHROUGH {
+				body = body[:l-1]
+				fallsThrough = true
+			}
+		}
+		p.body(body)
+		if !fallsThrough && !isTerminating(body) {
+			p.indent++
+			p.line("break;")
+			p.indent--
+		}
+	}
+	p.line("}")
+}
+
+// doWhileCond returns the loop condition of the given loop if it may be
+// printed as a do-while loop.
+func doWhileCond(stmt *ast.ForStmt, label string) (ast.Expr, bool) {
+	if stmt.Init != nil || stmt.Cond != nil || stmt.Post != nil {
+		return nil, false
+	}
+	body := stmt.Body.List
+	if len(body) == 0 {
+		return nil, false
+	}
+	// Locate trailing `if !cond { break }`.
+	ifStmt, ok := body[len(body)-1].(*ast.IfStmt)
+	if !ok || ifStmt.Init != nil || ifStmt.Else != nil || len(ifStmt.Body.List) != 1 {
+		return nil, false
+	}
+	branch, ok := ifStmt.Body.List[0].(*ast.BranchStmt)
+	if !ok || branch.Tok != token.BREAK || branch.Label != nil {
+		return nil, false
+	}
+	// A continue statement would skip the loop condition of the do-while loop.
+	if hasContinue(stmt.Body, label) {
+		return nil, false
+	}
+	return negate(ifStmt.Cond), true
+}
+
+// hasContinue reports whether the given loop body contains continue statements
+// targeting the loop with the given label (if any).
+func hasContinue(body *ast.BlockStmt, label string) bool {
+	found := false
+	var walk func(n ast.Node, nested bool)
+	walk = func(n ast.Node, nested bool) {
+		ast.Inspect(n, func(n ast.Node) bool {
+			switch n := n.(type) {
+			case *ast.ForStmt:
+				// Unlabelled continue statements of nested loops target the nested
+				// loop.
+				walk(n.Body, true)
+				return false
+			case *ast.BranchStmt:
+				if n.Tok != token.CONTINUE {
+					break
+				}
+				if (n.Label == nil && !nested) || (n.Label != nil && n.Label.Name == label) {
+					found = true
+				}
+			}
+			return true
+		})
+	}
+	walk(body, false)
+	return found
+}
+
+// isTerminating reports whether the given list of statements ends with a
+// return or branch statement.
+func isTerminating(stmts []ast.Stmt) bool {
+	if len(stmts) == 0 {
+		return false
+	}
+	switch stmts[len(stmts)-1].(type) {
+	case *ast.ReturnStmt, *ast.BranchStmt:
+		return true
+	}
+	return false
+}
+
+// negate returns the negation of the given boolean expression.
+func negate(cond ast.Expr) ast.Expr {
+	switch x := cond.(type) {
+	case *ast.UnaryExpr:
+		if x.Op == token.NOT {
+			if y, ok := x.X.(*ast.ParenExpr); ok {
+				return y.X
+			}
+			return x.X
+		}
+	case *ast.BinaryExpr:
+		switch x.Op {
+		case token.EQL:
+			return &ast.BinaryExpr{X: x.X, Op: token.NEQ, Y: x.Y}
+		case token.NEQ:
+			return &ast.BinaryExpr{X: x.X, Op: token.EQL, Y: x.Y}
+		case token.LAND, token.LOR:
+			return not(&ast.ParenExpr{X: x})
+		}
+	}
+	return not(cond)
+}
+
+// cExpr returns the C pseudo-code of the given expression.
+func cExpr(x ast.Expr) string {
+	switch x := x.(type) {
+	case *ast.Ident:
+		return x.Name
+	case *ast.BasicLit:
+		return x.Value
+	case *ast.ParenExpr:
+		return fmt.Sprintf("(%s)", cExpr(x.X))
+	case *ast.UnaryExpr:
+		if _, ok := x.X.(*ast.BinaryExpr); ok {
+			return fmt.Sprintf("%s(%s)", x.Op, cExpr(x.X))
+		}
+		return fmt.Sprintf("%s%s", x.Op, cExpr(x.X))
+	case *ast.BinaryExpr:
+		return fmt.Sprintf("%s %s %s", cExpr(x.X), x.Op, cExpr(x.Y))
+	default:
+		panic(fmt.Errorf("support for expression %T not yet implemented", x))
+	}
+}
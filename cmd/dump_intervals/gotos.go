@@ -0,0 +1,235 @@
+package main
+
+import (
+	"go/ast"
+	"go/token"
+)
+
+// minimizeGotos minimizes the number of goto statements of the given function,
+// as produced by genFunc, and returns the number of remaining goto statements.
+//
+// Goto statements targeting the header or the follow of an enclosing loop are
+// translated into continue and break statements, respectively. Branch
+// statements targeting the statement immediately following them are removed,
+// and so are labels not targeted by any branch statement.
+func minimizeGotos(f *ast.FuncDecl) int {
+	m := &minimizer{}
+	f.Body.List = m.stmts(f.Body.List, nil)
+	// Remove unused labels.
+	refs := make(map[string]bool)
+	ast.Inspect(f.Body, func(n ast.Node) bool {
+		if stmt, ok := n.(*ast.BranchStmt); ok && stmt.Label != nil {
+			refs[stmt.Label.Name] = true
+		}
+		return true
+	})
+	f.Body.List = unlabel(f.Body.List, refs)
+	// Count remaining goto statements.
+	ngotos := 0
+	ast.Inspect(f.Body, func(n ast.Node) bool {
+		if stmt, ok := n.(*ast.BranchStmt); ok && stmt.Tok == token.GOTO {
+			ngotos++
+		}
+		return true
+	})
+	return ngotos
+}
+
+// minimizer tracks the enclosing loops and switch statements of the statements
+// being minimized.
+type minimizer struct {
+	// Enclosing loops, from outermost to innermost.
+	loops []*loopInfo
+	// Number of enclosing loops of each enclosing switch statement, from
+	// outermost to innermost.
+	switches []int
+}
+
+// loopInfo records the labels of a loop.
+type loopInfo struct {
+	// Label of the loop; or empty if unlabelled.
+	label string
+	// Labels of the statement immediately following the loop.
+	follow []string
+}
+
+// stmts minimizes the given list of statements and returns the result. next
+// specifies the labels of the statement executed after the last statement of
+// the list.
+func (m *minimizer) stmts(stmts []ast.Stmt, next []string) []ast.Stmt {
+	var list []ast.Stmt
+	for i, stmt := range stmts {
+		stmtNext := nextLabels(stmts[i+1:], next)
+		if branch, ok := stmt.(*ast.BranchStmt); ok {
+			m.branch(branch)
+			// Remove branch statements targeting the next statement.
+			if target := m.target(branch); len(target) > 0 && contains(stmtNext, target) {
+				continue
+			}
+		} else {
+			m.stmt(stmt, stmtNext)
+		}
+		list = append(list, stmt)
+	}
+	return list
+}
+
+// stmt minimizes the given statement. next specifies the labels of the
+// statement executed after stmt.
+func (m *minimizer) stmt(stmt ast.Stmt, next []string) {
+	switch stmt := stmt.(type) {
+	case *ast.LabeledStmt:
+		if loop, ok := stmt.Stmt.(*ast.ForStmt); ok {
+			m.loop(loop, stmt.Label.Name, next)
+			return
+		}
+		m.stmt(stmt.Stmt, next)
+	case *ast.BlockStmt:
+		stmt.List = m.stmts(stmt.List, next)
+	case *ast.IfStmt:
+		stmt.Body.List = m.stmts(stmt.Body.List, next)
+		if stmt.Else != nil {
+			m.stmt(stmt.Else, next)
+		}
+	case *ast.ForStmt:
+		m.loop(stmt, "", next)
+	case *ast.SwitchStmt:
+		m.switches = append(m.switches, len(m.loops))
+		for _, s := range stmt.Body.List {
+			clause := s.(*ast.CaseClause)
+			clause.Body = m.stmts(clause.Body, next)
+		}
+		m.switches = m.switches[:len(m.switches)-1]
+	}
+}
+
+// loop minimizes the body of the given loop with the given label (if any).
+// follow specifies the labels of the statement immediately following the loop.
+func (m *minimizer) loop(stmt *ast.ForStmt, label string, follow []string) {
+	m.loops = append(m.loops, &loopInfo{label: label, follow: follow})
+	// The loop header is executed after the last statement of the loop body.
+	var next []string
+	if len(label) > 0 {
+		next = []string{label}
+	}
+	stmt.Body.List = m.stmts(stmt.Body.List, next)
+	m.loops = m.loops[:len(m.loops)-1]
+}
+
+// branch translates the given goto statement into a continue or break
+// statement if targeting the header or the follow of an enclosing loop,
+// respectively.
+func (m *minimizer) branch(stmt *ast.BranchStmt) {
+	if stmt.Tok != token.GOTO {
+		return
+	}
+	target := stmt.Label.Name
+	for i := len(m.loops) - 1; i >= 0; i-- {
+		loop := m.loops[i]
+		var tok token.Token
+		switch {
+		case target == loop.label:
+			tok = token.CONTINUE
+		case contains(loop.follow, target):
+			tok = token.BREAK
+		default:
+			continue
+		}
+		// Label the statement if the loop is not the innermost one, or if break
+		// would refer to an enclosed switch statement.
+		inSwitch := len(m.switches) > 0 && m.switches[len(m.switches)-1] > i
+		if i == len(m.loops)-1 && !(tok == token.BREAK && inSwitch) {
+			stmt.Tok = tok
+			stmt.Label = nil
+		} else if len(loop.label) > 0 {
+			stmt.Tok = tok
+			stmt.Label = ast.NewIdent(loop.label)
+		}
+		return
+	}
+}
+
+// target returns the label of the statement targeted by the given goto or
+// continue statement; or an empty string if unknown.
+func (m *minimizer) target(stmt *ast.BranchStmt) string {
+	switch stmt.Tok {
+	case token.GOTO:
+		return stmt.Label.Name
+	case token.CONTINUE:
+		if stmt.Label != nil {
+			return stmt.Label.Name
+		}
+		if l := len(m.loops); l > 0 {
+			return m.loops[l-1].label
+		}
+	}
+	return ""
+}
+
+// nextLabels returns the labels of the first statement of stmts, including the
+// labels of any labelled empty statements preceding it. next specifies the
+// labels of the statement executed after the last statement of stmts.
+func nextLabels(stmts []ast.Stmt, next []string) []string {
+	var labels []string
+	for _, stmt := range stmts {
+		labelStmt, ok := stmt.(*ast.LabeledStmt)
+		if !ok {
+			return labels
+		}
+		labels = append(labels, labelStmt.Label.Name)
+		if _, ok := labelStmt.Stmt.(*ast.EmptyStmt); !ok {
+			return labels
+		}
+	}
+	return append(labels, next...)
+}
+
+// unlabel removes the labels of the given list of statements, recursively, not
+// present in refs, and returns the result.
+func unlabel(stmts []ast.Stmt, refs map[string]bool) []ast.Stmt {
+	var list []ast.Stmt
+	for _, stmt := range stmts {
+		if labelStmt, ok := stmt.(*ast.LabeledStmt); ok && !refs[labelStmt.Label.Name] {
+			if _, ok := labelStmt.Stmt.(*ast.EmptyStmt); ok {
+				continue
+			}
+			stmt = labelStmt.Stmt
+		}
+		unlabelStmt(stmt, refs)
+		list = append(list, stmt)
+	}
+	return list
+}
+
+// unlabelStmt removes the labels of statements nested within the given
+// statement not present in refs.
+func unlabelStmt(stmt ast.Stmt, refs map[string]bool) {
+	switch stmt := stmt.(type) {
+	case *ast.LabeledStmt:
+		unlabelStmt(stmt.Stmt, refs)
+	case *ast.BlockStmt:
+		stmt.List = unlabel(stmt.List, refs)
+	case *ast.IfStmt:
+		stmt.Body.List = unlabel(stmt.Body.List, refs)
+		if stmt.Else != nil {
+			unlabelStmt(stmt.Else, refs)
+		}
+	case *ast.ForStmt:
+		stmt.Body.List = unlabel(stmt.Body.List, refs)
+	case *ast.SwitchStmt:
+		for _, s := range stmt.Body.List {
+			clause := s.(*ast.CaseClause)
+			clause.Body = unlabel(clause.Body, refs)
+		}
+	}
+}
+
+// contains reports whether the given list of labels contains label.
+func contains(labels []string, label string) bool {
+	for _, l := range labels {
+		if l == label {
+			return true
+		}
+	}
+	return false
+}
@@ -5,6 +5,7 @@ import (
 	"flag"
 	"fmt"
 	"go/ast"
+	"go/parser"
 	"go/printer"
 	"go/token"
 	"log"
@@ -17,6 +18,7 @@ import (
 	"github.com/graphism/exp/cfa"
 	"github.com/graphism/exp/cfg"
 	"github.com/graphism/exp/flow"
+	"github.com/graphism/exp/logic"
 	"github.com/mewkiz/pkg/term"
 	"github.com/pkg/errors"
 )
@@ -25,20 +27,38 @@ import (
 var dbg = log.New(os.Stderr, term.RedBold("interval:")+" ", 0)
 
 func main() {
+	var (
+		// Output derived sequence of graphs.
+		derived bool
+		// Output structured control flow graph.
+		structure bool
+		// Output language.
+		lang string
+		// Structuring mode.
+		mode string
+	)
+	flag.BoolVar(&derived, "derived", false, "output derived sequence of graphs in DOT format")
+	flag.BoolVar(&structure, "structure", false, "output structured control flow graph in DOT format (cifuentes mode only)")
+	flag.StringVar(&lang, "lang", "go", `output language ("go" or "c")`)
+	flag.StringVar(&mode, "mode", "cifuentes", `structuring mode ("cifuentes" or "nogotos")`)
 	flag.Parse()
 	for _, path := range flag.Args() {
-		if err := dumpIntervals(path); err != nil {
+		if err := dumpIntervals(path, derived, structure, lang, mode); err != nil {
 			log.Fatalf("%+v", err)
 		}
 	}
 }
 
-func dumpIntervals(path string) error {
+func dumpIntervals(path string, derived, structure bool, lang, mode string) error {
 	dbg.Printf("\n=== [ %s ] ===\n\n", path)
 	g, err := cfg.ParseFile(path)
 	if err != nil {
 		return errors.WithStack(err)
 	}
+	if derived {
+		fmt.Println(cfa.DerivedGraphSeqDOT(cfa.DerivedGraphSeq(g)))
+		return nil
+	}
 	is := flow.Intervals(g, g.Entry())
 	for _, i := range is {
 		dbg.Println("head:", i.Head)
@@ -49,25 +69,43 @@ func dumpIntervals(path string) error {
 		}
 	}
 	g = cfa.CompoundCond(g)
-	cfa.Structure(g)
-	//spew.Dump(g.Nodes())
-	f := genFunc(g)
+	var f *ast.FuncDecl
+	switch mode {
+	case "cifuentes":
+		cfa.Structure(g)
+		if structure {
+			fmt.Println(cfa.StructureDOT(g))
+			return nil
+		}
+		//spew.Dump(g.Nodes())
+		f = genFunc(g)
+	case "nogotos":
+		// Structuring independently of control flow patterns does not annotate
+		// the control flow graph.
+		if structure {
+			return errors.Errorf("support for structured control flow graph output in structuring mode %q not yet implemented", mode)
+		}
+		f = genFuncNoGotos(g)
+	default:
+		return errors.Errorf("support for structuring mode %q not yet implemented", mode)
+	}
 	//pretty.Println("f:", f)
+	ngotos := minimizeGotos(f)
+	dbg.Printf("%s: %d goto statements remaining", path, ngotos)
 	buf := &bytes.Buffer{}
-	if err := printer.Fprint(buf, token.NewFileSet(), f); err != nil {
-		return errors.WithStack(err)
+	switch lang {
+	case "go":
+		if err := printer.Fprint(buf, token.NewFileSet(), f); err != nil {
+			return errors.WithStack(err)
+		}
+	case "c":
+		if err := printC(buf, f); err != nil {
+			return errors.WithStack(err)
+		}
+	default:
+		return errors.Errorf("support for output language %q not yet implemented", lang)
 	}
 	fmt.Println(buf.String())
-
-	//gs := cfa.DerivedGraphSeq(g)
-	//for num, g := range gs {
-	//	name := fmt.Sprintf("G%d", num)
-	//	buf, err := dot.Marshal(g, name, "", "\t", false)
-	//	if err != nil {
-	//		return errors.WithStack(err)
-	//	}
-	//	fmt.Println(string(buf))
-	//}
 	return nil
 }
 
@@ -75,6 +113,25 @@ type generator struct {
 	g    *cfg.Graph
 	done map[graph.Node]bool
 	cur  *ast.BlockStmt
+	// Header nodes of the loops currently being generated, from outermost to
+	// innermost.
+	loops []*cfg.Node
+	// Switch statements currently being generated, from outermost to innermost.
+	switches []*switchStmt
+}
+
+// switchStmt tracks the state of a switch statement being generated.
+type switchStmt struct {
+	// Number of enclosing loops of the switch statement.
+	nloops int
+	// Case targets for which no code has been generated yet.
+	targets map[*cfg.Node]bool
+	// Case targets branched to by goto statements from other case clauses.
+	gotos map[*cfg.Node]bool
+	// Body of the case clause currently being generated.
+	clause *ast.BlockStmt
+	// Target of the next case clause; or nil if last.
+	next *cfg.Node
 }
 
 func genFunc(g *cfg.Graph) *ast.FuncDecl {
@@ -87,7 +144,7 @@ func genFunc(g *cfg.Graph) *ast.FuncDecl {
 	entry := node(g.Entry())
 	dbg.Println("entry:", entry)
 	dbg.Println("entry.Follow:", entry.LoopFollow)
-	gen.genCode(entry, entry.LoopFollow)
+	gen.genCode(entry, nil)
 	return &ast.FuncDecl{
 		Name: ast.NewIdent(name),
 		Type: &ast.FuncType{
@@ -105,8 +162,18 @@ func (gen *generator) genCode(n, ifFollow *cfg.Node) {
 		return
 	}
 
+	// Emit break or continue if the node exits or restarts an enclosing loop.
+	if gen.genLoopBranch(n) {
+		return
+	}
+
+	// Emit fallthrough or goto if the node is the target of another case clause.
+	label := nodeLabel(n)
+	if gen.genCaseBranch(n, label) {
+		return
+	}
+
 	// Check if code already generated for block.
-	label := ast.NewIdent(fmt.Sprintf("l_%s", unquote(n.DOTID())))
 	if gen.done[n] {
 		stmt := &ast.BranchStmt{
 			Tok:   token.GOTO,
@@ -117,38 +184,330 @@ func (gen *generator) genCode(n, ifFollow *cfg.Node) {
 	}
 	gen.done[n] = true
 
-	// TODO: Add support for loops.
+	// Loop.
+	if n.LoopHead == n && n.LoopType != cfg.LoopTypeNone {
+		gen.genLoop(n, ifFollow)
+		return
+	}
+	gen.genNode(n, label, ifFollow)
+}
+
+// genLoop generates code for the loop headed by n, and continues with the loop
+// follow node.
+func (gen *generator) genLoop(n, ifFollow *cfg.Node) {
+	g := gen.g
+	bak := gen.cur
+	body := &ast.BlockStmt{}
+	gen.cur = body
+	gen.loops = append(gen.loops, n)
+	stmt := &ast.ForStmt{
+		Body: body,
+	}
+	switch n.LoopType {
+	case cfg.LoopTypePreTest:
+		// for cond {}
+		//    header node is the loop condition; the successor of the header
+		//    node not being the follow node is the loop body.
+		dbg.Println("pre-test loop:", n.DOTID())
+		t := g.TrueTarget(n)
+		f := g.FalseTarget(n)
+		if t == n.LoopFollow {
+			stmt.Cond = negate(gen.cond(n))
+			gen.genCode(f, nil)
+		} else {
+			stmt.Cond = gen.cond(n)
+			gen.genCode(t, nil)
+		}
+	case cfg.LoopTypePostTest:
+		// for { ...; if !cond { break } }
+		//    loop condition is generated at the latch node.
+		dbg.Println("post-test loop:", n.DOTID())
+		gen.genNode(n, nil, nil)
+	case cfg.LoopTypeEndless:
+		// for {}
+		dbg.Println("endless loop:", n.DOTID())
+		gen.genNode(n, nil, nil)
+	default:
+		panic(fmt.Errorf("support for loop type %v not yet implemented", n.LoopType))
+	}
+	// Drop redundant continue statement at the end of the loop body.
+	if l := len(body.List); l > 0 {
+		if branch, ok := body.List[l-1].(*ast.BranchStmt); ok && branch.Tok == token.CONTINUE && branch.Label == nil {
+			body.List = body.List[:l-1]
+		}
+	}
+	gen.loops = gen.loops[:len(gen.loops)-1]
+	gen.cur = bak
+	labelStmt := &ast.LabeledStmt{
+		Label: nodeLabel(n),
+		Stmt:  stmt,
+	}
+	gen.cur.List = append(gen.cur.List, labelStmt)
+	// Continue with the follow.
+	if n.LoopFollow != nil {
+		dbg.Println("### >> n.LoopFollow", n.LoopFollow)
+		gen.genCode(n.LoopFollow, ifFollow)
+	}
+}
+
+// genLoopBranch emits a break or continue statement if n is the follow node or
+// the header node of an enclosing loop, respectively. The statement is labelled
+// if the loop is not the innermost one. The boolean return value indicates
+// whether a statement was emitted.
+func (gen *generator) genLoopBranch(n *cfg.Node) bool {
+	for i := len(gen.loops) - 1; i >= 0; i-- {
+		head := gen.loops[i]
+		stmt := &ast.BranchStmt{}
+		switch n {
+		case head:
+			stmt.Tok = token.CONTINUE
+		case head.LoopFollow:
+			stmt.Tok = token.BREAK
+		default:
+			continue
+		}
+		// Label the statement if the loop is not the innermost one, or if break
+		// would refer to an enclosed switch statement.
+		if i != len(gen.loops)-1 || (stmt.Tok == token.BREAK && gen.inSwitch(i)) {
+			stmt.Label = nodeLabel(head)
+		}
+		gen.cur.List = append(gen.cur.List, stmt)
+		return true
+	}
+	return false
+}
+
+// inSwitch reports whether a switch statement is currently being generated
+// within the i:th enclosing loop.
+func (gen *generator) inSwitch(i int) bool {
+	l := len(gen.switches)
+	return l > 0 && gen.switches[l-1].nloops > i
+}
 
+// genCaseBranch emits a fallthrough statement if n is the target of the next
+// case clause of the innermost switch statement, and a goto statement if n is
+// the target of any other case clause for which no code has been generated yet.
+// The boolean return value indicates whether a statement was emitted.
+func (gen *generator) genCaseBranch(n *cfg.Node, label *ast.Ident) bool {
+	l := len(gen.switches)
+	if l == 0 {
+		return false
+	}
+	s := gen.switches[l-1]
+	if !s.targets[n] {
+		return false
+	}
+	stmt := &ast.BranchStmt{}
+	if n == s.next && gen.cur == s.clause {
+		stmt.Tok = token.FALLTHROUGH
+	} else {
+		stmt.Tok = token.GOTO
+		stmt.Label = label
+		s.gotos[n] = true
+	}
+	gen.cur.List = append(gen.cur.List, stmt)
+	return true
+}
+
+// genSwitch generates code for the n-way conditional headed by n, and
+// continues with the switch follow node.
+func (gen *generator) genSwitch(n *cfg.Node, label *ast.Ident, ifFollow *cfg.Node) {
+	g := gen.g
+	dbg.Println("switch:", n.DOTID())
+	// Generate case clauses in reverse postorder of their targets, to allow
+	// for fallthrough between cases sharing targets.
+	targets := cfg.SortByRevPost(graph.NodesOf(g.From(n.ID())))
+	s := &switchStmt{
+		nloops:  len(gen.loops),
+		targets: make(map[*cfg.Node]bool),
+		gotos:   make(map[*cfg.Node]bool),
+	}
+	for _, target := range targets {
+		s.targets[target] = true
+	}
+	// Place the default clause last, unless its target is adjacent to the
+	// target of another case clause.
+	for i, target := range targets {
+		if caseExprs(edge(g.Edge(n.ID(), target.ID()))) != nil {
+			continue
+		}
+		adjacent := false
+		for _, other := range targets {
+			if other != target && (g.HasEdgeFromTo(other.ID(), target.ID()) || g.HasEdgeFromTo(target.ID(), other.ID())) {
+				adjacent = true
+			}
+		}
+		if !adjacent {
+			targets = append(append(targets[:i:i], targets[i+1:]...), target)
+		}
+		break
+	}
+	gen.switches = append(gen.switches, s)
+	bak := gen.cur
+	body := &ast.BlockStmt{}
+	var clauses []*ast.CaseClause
+	for i, target := range targets {
+		dbg.Println("   case:", target.DOTID())
+		e := edge(g.Edge(n.ID(), target.ID()))
+		clauseBody := &ast.BlockStmt{}
+		gen.cur = clauseBody
+		s.clause = clauseBody
+		s.next = nil
+		if i+1 < len(targets) {
+			s.next = targets[i+1]
+		}
+		delete(s.targets, target)
+		gen.genCode(target, n.SwitchFollow)
+		clause := &ast.CaseClause{
+			List: caseExprs(e),
+			Body: clauseBody.List,
+		}
+		body.List = append(body.List, clause)
+		clauses = append(clauses, clause)
+	}
+	gen.switches = gen.switches[:len(gen.switches)-1]
+	gen.cur = bak
+	gen.genLabel(label)
+	stmt := &ast.SwitchStmt{
+		Tag:  ast.NewIdent(cfa.TagSym(n)),
+		Body: body,
+	}
+	gen.cur.List = append(gen.cur.List, stmt)
+	gen.hoistCases(n, s, targets, clauses)
+	// Continue with the follow.
+	if n.SwitchFollow != nil {
+		dbg.Println("### >> n.SwitchFollow", n.SwitchFollow)
+		gen.genCode(n.SwitchFollow, ifFollow)
+	}
+}
+
+// hoistCases moves the code of case targets branched to by goto statements from
+// other case clauses out of the switch statement headed by n, as Go does not
+// permit goto statements into blocks. The hoisted code is placed after the
+// switch statement, and is skipped by a goto statement to the follow node when
+// the switch statement completes.
+func (gen *generator) hoistCases(n *cfg.Node, s *switchStmt, targets []*cfg.Node, clauses []*ast.CaseClause) {
+	if len(s.gotos) == 0 {
+		return
+	}
+	// Hoisted code falling through to the next case clause branches to it by
+	// goto instead, and thus the next case target is hoisted as well.
+	for change := true; change; {
+		change = false
+		for i, target := range targets {
+			if !s.gotos[target] {
+				continue
+			}
+			body := clauses[i].Body
+			l := len(body)
+			if l == 0 {
+				continue
+			}
+			if branch, ok := body[l-1].(*ast.BranchStmt); ok && branch.Tok == token.FALLTHROUGH {
+				next := targets[i+1]
+				body[l-1] = &ast.BranchStmt{Tok: token.GOTO, Label: nodeLabel(next)}
+				if !s.gotos[next] {
+					s.gotos[next] = true
+					change = true
+				}
+			}
+		}
+	}
+	var hoisted []ast.Stmt
+	for i, target := range targets {
+		if !s.gotos[target] {
+			continue
+		}
+		hoisted = append(hoisted, clauses[i].Body...)
+		clauses[i].Body = []ast.Stmt{&ast.BranchStmt{Tok: token.GOTO, Label: nodeLabel(target)}}
+	}
+	if n.SwitchFollow != nil {
+		skip := &ast.BranchStmt{Tok: token.GOTO, Label: nodeLabel(n.SwitchFollow)}
+		gen.cur.List = append(gen.cur.List, skip)
+	}
+	gen.cur.List = append(gen.cur.List, hoisted...)
+}
+
+// isLoopBranch reports whether n is the follow node or the header node of an
+// enclosing loop.
+func (gen *generator) isLoopBranch(n *cfg.Node) bool {
+	for _, head := range gen.loops {
+		if n == head || n == head.LoopFollow {
+			return true
+		}
+	}
+	return false
+}
+
+// genNode generates code for the node n, and continues with its successors. The
+// label statement is omitted if label is nil.
+func (gen *generator) genNode(n *cfg.Node, label *ast.Ident, ifFollow *cfg.Node) {
 	g := gen.g
 	succs := graph.NodesOf(g.From(n.ID()))
 	switch len(succs) {
 	// Return statement.
 	case 0:
-		labelStmt := &ast.LabeledStmt{
-			Label: label,
-			Stmt:  &ast.EmptyStmt{},
-		}
-		gen.cur.List = append(gen.cur.List, labelStmt)
+		gen.genLabel(label)
 		stmt := &ast.ReturnStmt{}
 		gen.cur.List = append(gen.cur.List, stmt)
 		return
 	// Sequence.
 	case 1:
-		labelStmt := &ast.LabeledStmt{
-			Label: label,
-			Stmt:  &ast.EmptyStmt{},
-		}
-		gen.cur.List = append(gen.cur.List, labelStmt)
+		gen.genLabel(label)
 		gen.genCode(node(succs[0]), ifFollow)
 		return
 	// Two-way conditional or loop.
 	case 2:
-		if n.IfFollow == nil {
-			panic(fmt.Errorf("support for unresolved 2-way nodes not yet supported; no follow node for %q", n.DOTID()))
+		// Latch node of post-test loop.
+		if l := len(gen.loops); l > 0 {
+			head := gen.loops[l-1]
+			if head.LoopType == cfg.LoopTypePostTest && head.Latch == n {
+				// if !cond { break }
+				dbg.Println("latch:", n.DOTID())
+				gen.genLabel(label)
+				cond := gen.cond(n)
+				if g.TrueTarget(n) == head {
+					cond = negate(cond)
+				}
+				stmt := &ast.IfStmt{
+					Cond: cond,
+					Body: &ast.BlockStmt{
+						List: []ast.Stmt{&ast.BranchStmt{Tok: token.BREAK}},
+					},
+				}
+				gen.cur.List = append(gen.cur.List, stmt)
+				return
+			}
 		}
 		bak := gen.cur
 		t := g.TrueTarget(n)
 		f := g.FalseTarget(n)
+		// Conditional exit or restart of an enclosing loop.
+		if n.IfFollow == nil && (gen.isLoopBranch(t) || gen.isLoopBranch(f)) {
+			// if cond { break }
+			cond := gen.cond(n)
+			target, other := t, f
+			if !gen.isLoopBranch(t) {
+				cond = negate(cond)
+				target, other = f, t
+			}
+			dbg.Println("loop exit:", n.DOTID())
+			body := &ast.BlockStmt{}
+			gen.cur = body
+			gen.genLoopBranch(target)
+			stmt := &ast.IfStmt{
+				Cond: cond,
+				Body: body,
+			}
+			gen.cur = bak
+			gen.genLabel(label)
+			gen.cur.List = append(gen.cur.List, stmt)
+			gen.genCode(other, ifFollow)
+			return
+		}
+		if n.IfFollow == nil {
+			panic(fmt.Errorf("support for unresolved 2-way nodes not yet supported; no follow node for %q", n.DOTID()))
+		}
 		switch {
 		case t == n.IfFollow && f == n.IfFollow:
 			panic("support for multiple edges to follow node not yet supported")
@@ -160,16 +519,12 @@ func (gen *generator) genCode(n, ifFollow *cfg.Node) {
 			body := &ast.BlockStmt{}
 			gen.cur = body
 			gen.genCode(f, n.IfFollow)
-			labelStmt := &ast.LabeledStmt{
-				Label: label,
-				Stmt:  &ast.EmptyStmt{},
-			}
 			stmt := &ast.IfStmt{
-				Cond: ast.NewIdent("cond"),
+				Cond: negate(gen.cond(n)),
 				Body: body,
 			}
 			gen.cur = bak
-			gen.cur.List = append(gen.cur.List, labelStmt)
+			gen.genLabel(label)
 			gen.cur.List = append(gen.cur.List, stmt)
 		case f == n.IfFollow:
 			// if-then
@@ -179,16 +534,12 @@ func (gen *generator) genCode(n, ifFollow *cfg.Node) {
 			body := &ast.BlockStmt{}
 			gen.cur = body
 			gen.genCode(t, n.IfFollow)
-			labelStmt := &ast.LabeledStmt{
-				Label: label,
-				Stmt:  &ast.EmptyStmt{},
-			}
 			stmt := &ast.IfStmt{
-				Cond: ast.NewIdent("cond"),
+				Cond: gen.cond(n),
 				Body: body,
 			}
 			gen.cur = bak
-			gen.cur.List = append(gen.cur.List, labelStmt)
+			gen.genLabel(label)
 			gen.cur.List = append(gen.cur.List, stmt)
 		default:
 			// if-else
@@ -201,27 +552,36 @@ func (gen *generator) genCode(n, ifFollow *cfg.Node) {
 			falseBody := &ast.BlockStmt{}
 			gen.cur = falseBody
 			gen.genCode(f, n.IfFollow)
-			labelStmt := &ast.LabeledStmt{
-				Label: label,
-				Stmt:  &ast.EmptyStmt{},
-			}
 			stmt := &ast.IfStmt{
-				Cond: ast.NewIdent("cond"),
+				Cond: gen.cond(n),
 				Body: trueBody,
 				Else: falseBody,
 			}
 			gen.cur = bak
-			gen.cur.List = append(gen.cur.List, labelStmt)
+			gen.genLabel(label)
 			gen.cur.List = append(gen.cur.List, stmt)
 		}
 		// Continue with the follow.
 		dbg.Println("### >> n.Follow", n.IfFollow)
-		gen.genCode(n.IfFollow, n.IfFollow.IfFollow)
+		gen.genCode(n.IfFollow, ifFollow)
+	// N-way conditional.
 	default:
-		panic(fmt.Errorf("support for node with %d successors not yet implemented", g.From(n.ID()).Len()))
+		gen.genSwitch(n, label, ifFollow)
 	}
 }
 
+// genLabel emits a label statement with the given label, unless nil.
+func (gen *generator) genLabel(label *ast.Ident) {
+	if label == nil {
+		return
+	}
+	labelStmt := &ast.LabeledStmt{
+		Label: label,
+		Stmt:  &ast.EmptyStmt{},
+	}
+	gen.cur.List = append(gen.cur.List, labelStmt)
+}
+
 // ### [ Helper functions ] ####################################################
 
 // node asserts that the given node is a control flow graph node.
@@ -232,6 +592,96 @@ func node(n graph.Node) *cfg.Node {
 	panic(fmt.Errorf("invalid node type; expected *cfg.Node, got %T", n))
 }
 
+// edge asserts that the given edge is a control flow graph edge.
+func edge(e graph.Edge) *cfg.Edge {
+	if e, ok := e.(*cfg.Edge); ok {
+		return e
+	}
+	panic(fmt.Errorf("invalid edge type; expected *cfg.Edge, got %T", e))
+}
+
+// caseExprs returns the case expressions of the given edge from the header node
+// of an n-way conditional; or nil for the default case.
+func caseExprs(e *cfg.Edge) []ast.Expr {
+	values, isDefault, err := e.Cases()
+	if err != nil {
+		panic(err)
+	}
+	if isDefault {
+		return nil
+	}
+	var exprs []ast.Expr
+	for _, x := range values {
+		exprs = append(exprs, ast.NewIdent(x))
+	}
+	return exprs
+}
+
+// cond returns the condition under which the true branch of the given 2-way
+// node is taken.
+func (gen *generator) cond(n *cfg.Node) ast.Expr {
+	return boolExpr(logic.Simplify(cfa.NodeCond(n)))
+}
+
+// nodeLabel returns the label of the given node.
+func nodeLabel(n *cfg.Node) *ast.Ident {
+	return ast.NewIdent(fmt.Sprintf("l_%s", unquote(n.DOTID())))
+}
+
+// not returns the negation of the given boolean expression.
+func not(cond ast.Expr) ast.Expr {
+	return &ast.UnaryExpr{
+		Op: token.NOT,
+		X:  cond,
+	}
+}
+
+// boolExpr returns the Go expression of the given boolean expression.
+func boolExpr(x logic.Expr) ast.Expr {
+	switch x := x.(type) {
+	case logic.Const:
+		return ast.NewIdent(x.String())
+	case logic.Sym:
+		expr, err := parser.ParseExpr(string(x))
+		if err != nil {
+			panic(fmt.Errorf("unable to parse symbol %q; %v", x, err))
+		}
+		return expr
+	case logic.Eq:
+		expr, err := parser.ParseExpr(x.String())
+		if err != nil {
+			panic(fmt.Errorf("unable to parse comparison %q; %v", x, err))
+		}
+		return expr
+	case *logic.Not:
+		return negate(boolExpr(x.X))
+	case *logic.And:
+		return binaryExpr(x.Xs, token.LAND)
+	case *logic.Or:
+		return binaryExpr(x.Xs, token.LOR)
+	default:
+		panic(fmt.Errorf("support for boolean expression %T not yet implemented", x))
+	}
+}
+
+// binaryExpr returns the Go expression of the given operands joined by op.
+func binaryExpr(xs []logic.Expr, op token.Token) ast.Expr {
+	var expr ast.Expr
+	for _, x := range xs {
+		y := boolExpr(x)
+		// Disjunctions bind weaker than conjunctions.
+		if _, ok := x.(*logic.Or); ok && op == token.LAND {
+			y = &ast.ParenExpr{X: y}
+		}
+		if expr == nil {
+			expr = y
+		} else {
+			expr = &ast.BinaryExpr{X: expr, Op: op, Y: y}
+		}
+	}
+	return expr
+}
+
 // unquote returns an unquoted version of s.
 func unquote(s string) string {
 	if strings.HasPrefix(s, `"`) && strings.HasSuffix(s, `"`) {
@@ -0,0 +1,346 @@
+package main
+
+import (
+	"bytes"
+	"go/ast"
+	"go/parser"
+	"go/printer"
+	"go/token"
+	"io/ioutil"
+	"strings"
+	"testing"
+
+	"github.com/graphism/exp/cfa"
+	"github.com/graphism/exp/cfg"
+)
+
+func TestGenFunc(t *testing.T) {
+	golden := []struct {
+		path string
+		want string
+		// Number of goto statements remaining after minimization.
+		gotos int
+	}{
+		{path: "testdata/while.dot", want: "testdata/while.dot.golden", gotos: 0},
+		{path: "testdata/do_while.dot", want: "testdata/do_while.dot.golden", gotos: 0},
+		{path: "testdata/endless.dot", want: "testdata/endless.dot.golden", gotos: 0},
+		{path: "testdata/nested.dot", want: "testdata/nested.dot.golden", gotos: 0},
+		{path: "testdata/self_loop.dot", want: "testdata/self_loop.dot.golden", gotos: 0},
+		{path: "testdata/switch.dot", want: "testdata/switch.dot.golden", gotos: 0},
+		{path: "testdata/switch_loop.dot", want: "testdata/switch_loop.dot.golden", gotos: 0},
+		{path: "testdata/switch_shared.dot", want: "testdata/switch_shared.dot.golden", gotos: 2},
+		{path: "testdata/compound.dot", want: "testdata/compound.dot.golden", gotos: 0},
+	}
+	for _, gold := range golden {
+		g, err := cfg.ParseFile(gold.path)
+		if err != nil {
+			t.Errorf("%q; unable to parse file; %v", gold.path, err)
+			continue
+		}
+		buf, err := ioutil.ReadFile(gold.want)
+		if err != nil {
+			t.Errorf("%q; unable to read file; %v", gold.path, err)
+			continue
+		}
+		want := strings.TrimSpace(string(buf))
+		g = cfa.CompoundCond(g)
+		cfa.Structure(g)
+		f := genFunc(g)
+		out := &bytes.Buffer{}
+		if err := printer.Fprint(out, token.NewFileSet(), f); err != nil {
+			t.Errorf("%q; unable to print function; %v", gold.path, err)
+			continue
+		}
+		got := strings.TrimSpace(out.String())
+		if got != want {
+			t.Errorf("%q; output mismatch; expected `%s`, got `%s`", gold.path, want, got)
+			continue
+		}
+		if invalid := invalidGotos(f.Body.List, nil); len(invalid) > 0 {
+			t.Errorf("%q; goto statements into blocks; %v", gold.path, invalid)
+			continue
+		}
+		if ngotos := minimizeGotos(f); ngotos != gold.gotos {
+			t.Errorf("%q; number of goto statements mismatch; expected %d, got %d", gold.path, gold.gotos, ngotos)
+			continue
+		}
+	}
+}
+
+func TestGenFuncNoGotos(t *testing.T) {
+	golden := []struct {
+		path string
+		want string
+	}{
+		{path: "testdata/while.dot", want: "testdata/while.dot.nogotos.golden"},
+		{path: "testdata/do_while.dot", want: "testdata/do_while.dot.nogotos.golden"},
+		{path: "testdata/endless.dot", want: "testdata/endless.dot.nogotos.golden"},
+		{path: "testdata/if_else.dot", want: "testdata/if_else.dot.nogotos.golden"},
+		{path: "testdata/switch.dot", want: "testdata/switch.dot.nogotos.golden"},
+		{path: "testdata/switch_loop.dot", want: "testdata/switch_loop.dot.nogotos.golden"},
+		{path: "testdata/compound.dot", want: "testdata/compound.dot.nogotos.golden"},
+		{path: "testdata/multi_exit.dot", want: "testdata/multi_exit.dot.nogotos.golden"},
+		{path: "testdata/irreducible.dot", want: "testdata/irreducible.dot.nogotos.golden"},
+	}
+	for _, gold := range golden {
+		g, err := cfg.ParseFile(gold.path)
+		if err != nil {
+			t.Errorf("%q; unable to parse file; %v", gold.path, err)
+			continue
+		}
+		buf, err := ioutil.ReadFile(gold.want)
+		if err != nil {
+			t.Errorf("%q; unable to read file; %v", gold.path, err)
+			continue
+		}
+		want := strings.TrimSpace(string(buf))
+		g = cfa.CompoundCond(g)
+		f := genFuncNoGotos(g)
+		out := &bytes.Buffer{}
+		if err := printer.Fprint(out, token.NewFileSet(), f); err != nil {
+			t.Errorf("%q; unable to print function; %v", gold.path, err)
+			continue
+		}
+		got := strings.TrimSpace(out.String())
+		if got != want {
+			t.Errorf("%q; output mismatch; expected `%s`, got `%s`", gold.path, want, got)
+			continue
+		}
+		if ngotos := minimizeGotos(f); ngotos != 0 {
+			t.Errorf("%q; number of goto statements mismatch; expected 0, got %d", gold.path, ngotos)
+			continue
+		}
+	}
+}
+
+func TestDumpIntervalsUnsupported(t *testing.T) {
+	golden := []struct {
+		path      string
+		structure bool
+		mode      string
+	}{
+		// Structured control flow graph output in nogotos mode.
+		{path: "testdata/if_else.dot", structure: true, mode: "nogotos"},
+		// Unknown structuring mode.
+		{path: "testdata/if_else.dot", mode: "foo"},
+	}
+	for _, gold := range golden {
+		if err := dumpIntervals(gold.path, false, gold.structure, "go", gold.mode); err == nil {
+			t.Errorf("%q; expected error for structuring mode %q (structure=%v), got nil", gold.path, gold.mode, gold.structure)
+		}
+	}
+}
+
+func TestPrintC(t *testing.T) {
+	golden := []struct {
+		path string
+		want string
+	}{
+		{path: "testdata/while.dot", want: "testdata/while.dot.c.golden"},
+		{path: "testdata/do_while.dot", want: "testdata/do_while.dot.c.golden"},
+		{path: "testdata/nested.dot", want: "testdata/nested.dot.c.golden"},
+		{path: "testdata/switch.dot", want: "testdata/switch.dot.c.golden"},
+		{path: "testdata/switch_loop.dot", want: "testdata/switch_loop.dot.c.golden"},
+		{path: "testdata/compound.dot", want: "testdata/compound.dot.c.golden"},
+	}
+	for _, gold := range golden {
+		g, err := cfg.ParseFile(gold.path)
+		if err != nil {
+			t.Errorf("%q; unable to parse file; %v", gold.path, err)
+			continue
+		}
+		buf, err := ioutil.ReadFile(gold.want)
+		if err != nil {
+			t.Errorf("%q; unable to read file; %v", gold.path, err)
+			continue
+		}
+		want := strings.TrimSpace(string(buf))
+		g = cfa.CompoundCond(g)
+		cfa.Structure(g)
+		f := genFunc(g)
+		out := &bytes.Buffer{}
+		if err := printC(out, f); err != nil {
+			t.Errorf("%q; unable to print function; %v", gold.path, err)
+			continue
+		}
+		got := strings.TrimSpace(out.String())
+		if got != want {
+			t.Errorf("%q; output mismatch; expected `%s`, got `%s`", gold.path, want, got)
+			continue
+		}
+	}
+}
+
+func TestMinimizeGotos(t *testing.T) {
+	golden := []struct {
+		in     string
+		want   string
+		ngotos int
+	}{
+		// Unused labels.
+		{
+			in: `func f() {
+l_A:
+	;
+l_B:
+	for cond {
+	l_C:
+	}
+l_D:
+	;
+	return
+}`,
+			want: `func f() {
+	for cond {
+	}
+	return
+}`,
+			ngotos: 0,
+		},
+		// Goto statements targeting loop headers and follows.
+		{
+			in: `func f() {
+l_A:
+	;
+l_B:
+	for {
+	l_C:
+		;
+		if cond {
+			goto l_E
+		}
+	l_D:
+		;
+		switch x {
+		case 1:
+			goto l_E
+		case 2:
+			goto l_B
+		}
+		goto l_B
+	}
+l_E:
+	;
+	goto l_A
+}`,
+			want: `func f() {
+l_A:
+	;
+l_B:
+	for {
+		if cond {
+			break
+		}
+		switch x {
+		case 1:
+			break l_B
+		case 2:
+			continue
+		}
+	}
+	goto l_A
+}`,
+			ngotos: 1,
+		},
+		// Goto statements targeting the next statement.
+		{
+			in: `func f() {
+l_A:
+	;
+	if cond {
+	l_B:
+		;
+		goto l_D
+	} else {
+	l_C:
+		;
+		goto l_A
+	}
+l_D:
+	;
+	return
+}`,
+			want: `func f() {
+l_A:
+	;
+	if cond {
+	} else {
+		goto l_A
+	}
+	return
+}`,
+			ngotos: 1,
+		},
+	}
+	for _, gold := range golden {
+		file, err := parser.ParseFile(token.NewFileSet(), "", "package p\n"+gold.in, 0)
+		if err != nil {
+			t.Errorf("unable to parse function; %v", err)
+			continue
+		}
+		f := file.Decls[0].(*ast.FuncDecl)
+		ngotos := minimizeGotos(f)
+		out := &bytes.Buffer{}
+		if err := printer.Fprint(out, token.NewFileSet(), f); err != nil {
+			t.Errorf("unable to print function; %v", err)
+			continue
+		}
+		got := out.String()
+		if got != gold.want {
+			t.Errorf("output mismatch; expected `%s`, got `%s`", gold.want, got)
+			continue
+		}
+		if ngotos != gold.ngotos {
+			t.Errorf("number of goto statements mismatch; expected %d, got %d", gold.ngotos, ngotos)
+			continue
+		}
+	}
+}
+
+// invalidGotos returns the labels of the goto statements of the given list of
+// statements branching into a block, which Go does not permit; i.e. to labels
+// not declared in the list or in any of the enclosing lists of scopes.
+func invalidGotos(stmts []ast.Stmt, scopes []map[string]bool) []string {
+	scope := make(map[string]bool)
+	for _, stmt := range stmts {
+		for l, ok := stmt.(*ast.LabeledStmt); ok; l, ok = l.Stmt.(*ast.LabeledStmt) {
+			scope[l.Label.Name] = true
+		}
+	}
+	scopes = append(scopes, scope)
+	var invalid []string
+	var walk func(stmt ast.Stmt)
+	walk = func(stmt ast.Stmt) {
+		switch stmt := stmt.(type) {
+		case *ast.LabeledStmt:
+			walk(stmt.Stmt)
+		case *ast.BranchStmt:
+			if stmt.Tok != token.GOTO {
+				return
+			}
+			for _, scope := range scopes {
+				if scope[stmt.Label.Name] {
+					return
+				}
+			}
+			invalid = append(invalid, stmt.Label.Name)
+		case *ast.BlockStmt:
+			invalid = append(invalid, invalidGotos(stmt.List, scopes)...)
+		case *ast.IfStmt:
+			walk(stmt.Body)
+			if stmt.Else != nil {
+				walk(stmt.Else)
+			}
+		case *ast.ForStmt:
+			walk(stmt.Body)
+		case *ast.SwitchStmt:
+			for _, clause := range stmt.Body.List {
+				invalid = append(invalid, invalidGotos(clause.(*ast.CaseClause).Body, scopes)...)
+			}
+		}
+	}
+	for _, stmt := range stmts {
+		walk(stmt)
+	}
+	return invalid
+}
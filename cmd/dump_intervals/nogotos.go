@@ -0,0 +1,595 @@
+// ref: Yakdan, Khaled, et al. "No More Gotos: Decompilation Using
+// Pattern-Independent Control-Flow Structuring and Semantics-Preserving
+// Transformations." NDSS. 2015 [1].
+//
+// [1]: https://www.ndss-symposium.org/wp-content/uploads/2017/09/11_4_2.pdf
+
+package main
+
+import (
+	"fmt"
+	"go/ast"
+	"go/token"
+	"sort"
+	"strconv"
+
+	"github.com/graphism/exp/cfa"
+	"github.com/graphism/exp/cfg"
+	"github.com/graphism/exp/logic"
+	"gonum.org/v1/gonum/graph"
+)
+
+// genFuncNoGotos returns a goto-free function for the given control flow graph,
+// structured independently of control flow patterns.
+//
+// Cyclic regions are restructured into endless loops with explicit break
+// statements, and acyclic regions are structured based on the reaching
+// conditions of their nodes. Cyclic regions with multiple entries are first
+// given a single entry, which dispatches on a variable recording the original
+// entry to enter.
+//
+// The condition of each 2-way node A is denoted c_A, and the tag variable of
+// each n-way node A is denoted x_A. The conditions of compound condition nodes
+// are expanded to their boolean expressions.
+func genFuncNoGotos(g *cfg.Graph) *ast.FuncDecl {
+	name := fmt.Sprintf("f_%s", unquote(g.DOTID()))
+	s := &structurer{conds: cfa.CompoundConds(g)}
+	rs, entry := s.initRegions(g)
+	return &ast.FuncDecl{
+		Name: ast.NewIdent(name),
+		Type: &ast.FuncType{
+			Params: &ast.FieldList{},
+		},
+		Body: &ast.BlockStmt{
+			List: s.structure(rs, entry),
+		},
+	}
+}
+
+// structurer tracks the state of goto-free structuring.
+type structurer struct {
+	// Compound conditions, keyed by condition symbol.
+	conds map[logic.Sym]logic.Expr
+	// Number of regions created.
+	nregions int64
+}
+
+// newRegion returns a new region with the given name and structured code.
+func (s *structurer) newRegion(name string, stmts ...ast.Stmt) *region {
+	r := &region{id: s.nregions, name: name, stmts: stmts}
+	s.nregions++
+	return r
+}
+
+// region is a node of the graph being structured; either a basic block of the
+// control flow graph, or a collapsed cyclic region.
+type region struct {
+	// Region ID.
+	id int64
+	// Region name.
+	name string
+	// Structured code of the region.
+	stmts []ast.Stmt
+	// Outgoing branches of the region.
+	succs []*branch
+}
+
+// branch is a conditional edge between regions.
+type branch struct {
+	// Target region.
+	target *region
+	// Condition under which the branch is taken once its source region has been
+	// executed.
+	cond logic.Expr
+}
+
+// initRegions returns the regions of the basic blocks of g, in reverse
+// postorder, and the entry region.
+func (s *structurer) initRegions(g *cfg.Graph) ([]*region, *region) {
+	cfg.InitDFSOrder(g)
+	nodes := cfg.SortByRevPost(graph.NodesOf(g.Nodes()))
+	regions := make(map[*cfg.Node]*region)
+	var rs []*region
+	for _, n := range nodes {
+		r := s.newRegion(unquote(n.DOTID()), labelStmt(nodeLabel(n)))
+		regions[n] = r
+		rs = append(rs, r)
+	}
+	for _, n := range nodes {
+		r := regions[n]
+		succs := cfg.SortByRevPost(graph.NodesOf(g.From(n.ID())))
+		switch len(succs) {
+		// Return statement.
+		case 0:
+			r.stmts = append(r.stmts, &ast.ReturnStmt{})
+		// Place the true branch of 2-way conditionals first.
+		case 2:
+			succs = []*cfg.Node{g.TrueTarget(n), g.FalseTarget(n)}
+		}
+		for _, succ := range succs {
+			r.addBranch(regions[succ], cfa.EdgeCond(g, n, succ))
+		}
+	}
+	return rs, regions[node(g.Entry())]
+}
+
+// addBranch adds a branch from r to the given target region, taken under the
+// given condition.
+func (r *region) addBranch(target *region, cond logic.Expr) {
+	r.succs = append(r.succs, &branch{target: target, cond: cond})
+}
+
+// ID returns the ID of the region.
+func (r *region) ID() int64 {
+	return r.id
+}
+
+// regionGraph is the graph of regions, with conditional branches.
+type regionGraph struct{}
+
+// Succs returns the targets of the branches of n in reverse order, so that the
+// regions reached through the first branch of a region precede those reached
+// through the other branches in reverse postorder.
+func (regionGraph) Succs(n graph.Node) []graph.Node {
+	r := n.(*region)
+	var succs []graph.Node
+	for i := len(r.succs) - 1; i >= 0; i-- {
+		succs = append(succs, r.succs[i].target)
+	}
+	return succs
+}
+
+// EdgeCond returns the condition under which a branch from the region from to
+// the region to is taken.
+func (regionGraph) EdgeCond(from, to graph.Node) logic.Expr {
+	var conds []logic.Expr
+	for _, b := range from.(*region).succs {
+		if b.target == to {
+			conds = append(conds, b.cond)
+		}
+	}
+	return logic.NewOr(conds...)
+}
+
+// structure returns the structured code of the given regions, as entered
+// through entry. The branches of the regions must only target regions in rs.
+func (s *structurer) structure(rs []*region, entry *region) []ast.Stmt {
+	// Restructure cyclic regions into endless loops.
+	for _, scc := range cycles(rs) {
+		rs, entry = s.collapseLoop(rs, entry, scc)
+	}
+	// Structure the remaining acyclic region, guarding each region by its
+	// reaching condition; i.e. the condition under which it is reached from
+	// entry.
+	conds := cfa.ReachingConds(regionGraph{}, entry)
+	var items []*guarded
+	for _, r := range revPostorder(entry) {
+		// Skip regions which cannot be reached.
+		if conds[r] == logic.False {
+			continue
+		}
+		items = append(items, &guarded{cond: conds[r], stmts: r.stmts})
+	}
+	return s.refine(items)
+}
+
+// collapseLoop restructures the given strongly connected component of rs into
+// an endless loop, and returns the updated list of regions and entry region.
+func (s *structurer) collapseLoop(rs []*region, entry *region, scc []*region) ([]*region, *region) {
+	in := make(map[*region]bool)
+	for _, r := range scc {
+		in[r] = true
+	}
+	// Locate the entries of the cyclic region.
+	entered := map[*region]bool{entry: in[entry]}
+	for _, r := range rs {
+		if in[r] {
+			continue
+		}
+		for _, b := range r.succs {
+			if in[b.target] {
+				entered[b.target] = true
+			}
+		}
+	}
+	var entries []*region
+	for _, r := range scc {
+		if entered[r] {
+			entries = append(entries, r)
+		}
+	}
+	head := entries[0]
+	if len(entries) > 1 {
+		rs, entry, scc, head = s.dispatch(rs, entry, scc, entries)
+		in[head] = true
+		for _, r := range scc {
+			in[r] = true
+		}
+	}
+	// Locate the exit targets of the cyclic region.
+	var exits []*region
+	exitIndex := make(map[*region]int)
+	for _, r := range scc {
+		for _, b := range r.succs {
+			if _, ok := exitIndex[b.target]; !in[b.target] && !ok {
+				exitIndex[b.target] = len(exits)
+				exits = append(exits, b.target)
+			}
+		}
+	}
+	// Record the exit taken in a variable if the loop has multiple exit
+	// targets.
+	exitVar := "exit_" + head.name
+	exitConds := choice(exitVar, len(exits))
+	// Restructure branches to the header into continue statements, and
+	// branches to exit targets into break statements.
+	cont := s.newRegion("", &ast.BranchStmt{Tok: token.CONTINUE})
+	body := append(scc[:len(scc):len(scc)], cont)
+	var breaks []*region
+	for i := range exits {
+		brk := s.newRegion("")
+		if len(exits) > 1 {
+			brk.stmts = append(brk.stmts, assignStmt(exitVar, i))
+		}
+		brk.stmts = append(brk.stmts, &ast.BranchStmt{Tok: token.BREAK})
+		breaks = append(breaks, brk)
+		body = append(body, brk)
+	}
+	for _, r := range scc {
+		for _, b := range r.succs {
+			switch {
+			case b.target == head:
+				b.target = cont
+			case !in[b.target]:
+				b.target = breaks[exitIndex[b.target]]
+			}
+		}
+	}
+	loop := s.newRegion(head.name, loopStmt(s.structure(body, head)))
+	for i, exit := range exits {
+		loop.addBranch(exit, exitConds[i])
+	}
+	// Replace the cyclic region with the loop.
+	var list []*region
+	for _, r := range rs {
+		if r == head {
+			list = append(list, loop)
+		}
+		if in[r] {
+			continue
+		}
+		for _, b := range r.succs {
+			if b.target == head {
+				b.target = loop
+			}
+		}
+		list = append(list, r)
+	}
+	if entry == head {
+		entry = loop
+	}
+	return list, entry
+}
+
+// dispatch gives the strongly connected component of rs a single entry, by
+// redirecting all branches to its entries through a dispatch region which
+// branches on a variable recording the entry to enter. dispatch returns the
+// updated list of regions, entry region and strongly connected component, and
+// the dispatch region.
+func (s *structurer) dispatch(rs []*region, entry *region, scc, entries []*region) ([]*region, *region, []*region, *region) {
+	in := make(map[*region]bool)
+	for _, r := range scc {
+		in[r] = true
+	}
+	d := s.newRegion("d_" + entries[0].name)
+	entryVar := "entry_" + entries[0].name
+	entryIndex := make(map[*region]int)
+	for i, cond := range choice(entryVar, len(entries)) {
+		entryIndex[entries[i]] = i
+		d.addBranch(entries[i], cond)
+	}
+	// Record the entry to enter before branching to the dispatch region. Within
+	// the cyclic region, the assignment is immediately followed by a continue
+	// statement, as the dispatch region is to become the loop header; this
+	// prevents the conditions of the current iteration from referring to the
+	// updated variable.
+	assign := func(i int, inLoop bool) *region {
+		a := s.newRegion("", assignStmt(entryVar, i))
+		if inLoop {
+			a.stmts = append(a.stmts, &ast.BranchStmt{Tok: token.CONTINUE})
+		} else {
+			a.addBranch(d, logic.True)
+		}
+		return a
+	}
+	list := rs[:len(rs):len(rs)]
+	for _, r := range rs {
+		for _, b := range r.succs {
+			i, ok := entryIndex[b.target]
+			if !ok {
+				continue
+			}
+			a := assign(i, in[r])
+			b.target = a
+			list = append(list, a)
+			if in[r] {
+				scc = append(scc, a)
+			}
+		}
+	}
+	if i, ok := entryIndex[entry]; ok {
+		entry = assign(i, false)
+		list = append(list, entry)
+	}
+	list = append(list, d)
+	scc = append(scc, d)
+	return list, entry, scc, d
+}
+
+// cycles returns the strongly connected components of the given regions which
+// contain cycles, based on Tarjan's algorithm.
+func cycles(rs []*region) [][]*region {
+	pos := make(map[*region]int)
+	for i, r := range rs {
+		pos[r] = i
+	}
+	index := make(map[*region]int)
+	low := make(map[*region]int)
+	onStack := make(map[*region]bool)
+	var stack []*region
+	var sccs [][]*region
+	var walk func(r *region)
+	walk = func(r *region) {
+		index[r] = len(index)
+		low[r] = index[r]
+		stack = append(stack, r)
+		onStack[r] = true
+		cyclic := false
+		for _, b := range r.succs {
+			succ := b.target
+			if succ == r {
+				cyclic = true
+			}
+			if _, ok := index[succ]; !ok {
+				walk(succ)
+				if low[succ] < low[r] {
+					low[r] = low[succ]
+				}
+			} else if onStack[succ] && index[succ] < low[r] {
+				low[r] = index[succ]
+			}
+		}
+		if low[r] != index[r] {
+			return
+		}
+		var scc []*region
+		for {
+			top := stack[len(stack)-1]
+			stack = stack[:len(stack)-1]
+			onStack[top] = false
+			scc = append(scc, top)
+			if top == r {
+				break
+			}
+		}
+		if len(scc) > 1 || cyclic {
+			sort.Slice(scc, func(i, j int) bool {
+				return pos[scc[i]] < pos[scc[j]]
+			})
+			sccs = append(sccs, scc)
+		}
+	}
+	for _, r := range rs {
+		if _, ok := index[r]; !ok {
+			walk(r)
+		}
+	}
+	return sccs
+}
+
+// revPostorder returns the regions reachable from entry in reverse postorder.
+// Successors are visited in reverse order, so that the regions reached through
+// the first branch of a region precede those reached through the other
+// branches.
+func revPostorder(entry *region) []*region {
+	visited := make(map[*region]bool)
+	var order []*region
+	var walk func(r *region)
+	walk = func(r *region) {
+		visited[r] = true
+		for i := len(r.succs) - 1; i >= 0; i-- {
+			if succ := r.succs[i].target; !visited[succ] {
+				walk(succ)
+			}
+		}
+		order = append(order, r)
+	}
+	walk(entry)
+	for i, j := 0, len(order)-1; i < j; i, j = i+1, j-1 {
+		order[i], order[j] = order[j], order[i]
+	}
+	return order
+}
+
+// guarded is a list of statements guarded by a condition.
+type guarded struct {
+	cond  logic.Expr
+	stmts []ast.Stmt
+}
+
+// refine returns the code of the given sequence of guarded statement lists,
+// grouping consecutive statement lists with complementary conditions into
+// if-else statements.
+func (s *structurer) refine(items []*guarded) []ast.Stmt {
+	var stmts []ast.Stmt
+	for i := 0; i < len(items); {
+		item := items[i]
+		if item.cond == logic.True {
+			stmts = append(stmts, item.stmts...)
+			i++
+			continue
+		}
+		// Locate the literal shared by the longest run of consecutive items,
+		// either in positive or negated form.
+		var best logic.Expr
+		n := 0
+		for _, l := range logic.Common(item.cond) {
+			j := i
+			for j < len(items) && (logic.Entails(items[j].cond, l) || logic.Entails(items[j].cond, logic.NewNot(l))) {
+				j++
+			}
+			if j-i > n {
+				best, n = l, j-i
+			}
+		}
+		if n == 0 {
+			stmt := &ast.IfStmt{
+				Cond: s.condExpr(item.cond),
+				Body: &ast.BlockStmt{List: item.stmts},
+			}
+			stmts = append(stmts, stmt)
+			i++
+			continue
+		}
+		var then, els []*guarded
+		for _, item := range items[i : i+n] {
+			if logic.Entails(item.cond, best) {
+				then = append(then, &guarded{cond: logic.Without(item.cond, best), stmts: item.stmts})
+			} else {
+				els = append(els, &guarded{cond: logic.Without(item.cond, logic.NewNot(best)), stmts: item.stmts})
+			}
+		}
+		stmts = append(stmts, ifElse(s.condExpr(best), s.refine(then), s.refine(els))...)
+		i += n
+	}
+	return stmts
+}
+
+// ifElse returns an if-else statement with the given condition and branches.
+// The else branch is dropped if either branch is terminating, by placing the
+// other branch after the if-statement.
+func ifElse(cond ast.Expr, then, els []ast.Stmt) []ast.Stmt {
+	ifStmt := func(cond ast.Expr, body []ast.Stmt) *ast.IfStmt {
+		return &ast.IfStmt{
+			Cond: cond,
+			Body: &ast.BlockStmt{List: body},
+		}
+	}
+	switch {
+	case len(els) == 0:
+		return []ast.Stmt{ifStmt(cond, then)}
+	case len(then) == 0:
+		return []ast.Stmt{ifStmt(negate(cond), els)}
+	// Prefer to guard the branch not ending with a continue statement, as it
+	// is likely to be redundant at the end of a loop body.
+	case isTerminating(els) && (!isTerminating(then) || isContinue(then)):
+		return append([]ast.Stmt{ifStmt(negate(cond), els)}, then...)
+	case isTerminating(then):
+		return append([]ast.Stmt{ifStmt(cond, then)}, els...)
+	}
+	stmt := ifStmt(cond, then)
+	stmt.Else = &ast.BlockStmt{List: els}
+	return []ast.Stmt{stmt}
+}
+
+// loopStmt returns an endless loop with the given body, refined into a
+// pre-tested loop if the loop body starts with a conditional break statement,
+// optionally preceded by the label of the loop header.
+func loopStmt(body []ast.Stmt) ast.Stmt {
+	// Drop redundant continue statement at the end of the loop body.
+	if isContinue(body) {
+		body = body[:len(body)-1]
+	}
+	stmt := &ast.ForStmt{
+		Body: &ast.BlockStmt{List: body},
+	}
+	var label *ast.Ident
+	if len(body) > 0 {
+		if labelStmt, ok := body[0].(*ast.LabeledStmt); ok {
+			if _, ok := labelStmt.Stmt.(*ast.EmptyStmt); ok {
+				label = labelStmt.Label
+				body = body[1:]
+			}
+		}
+	}
+	if cond, ok := breakCond(body); ok {
+		// for !cond {}
+		stmt.Cond = negate(cond)
+		stmt.Body.List = body[1:]
+		if label != nil {
+			return &ast.LabeledStmt{Label: label, Stmt: stmt}
+		}
+	}
+	return stmt
+}
+
+// breakCond returns the condition of the unlabelled conditional break statement
+// at the start of the given list of statements, and a boolean variable
+// indicating success.
+func breakCond(stmts []ast.Stmt) (ast.Expr, bool) {
+	if len(stmts) == 0 {
+		return nil, false
+	}
+	ifStmt, ok := stmts[0].(*ast.IfStmt)
+	if !ok || ifStmt.Else != nil || len(ifStmt.Body.List) != 1 {
+		return nil, false
+	}
+	branch, ok := ifStmt.Body.List[0].(*ast.BranchStmt)
+	if !ok || branch.Tok != token.BREAK || branch.Label != nil {
+		return nil, false
+	}
+	return ifStmt.Cond, true
+}
+
+// isContinue reports whether the given list of statements ends with an
+// unlabelled continue statement.
+func isContinue(stmts []ast.Stmt) bool {
+	if len(stmts) == 0 {
+		return false
+	}
+	branch, ok := stmts[len(stmts)-1].(*ast.BranchStmt)
+	return ok && branch.Tok == token.CONTINUE && branch.Label == nil
+}
+
+// labelStmt returns a label statement with the given label.
+func labelStmt(label *ast.Ident) *ast.LabeledStmt {
+	return &ast.LabeledStmt{
+		Label: label,
+		Stmt:  &ast.EmptyStmt{},
+	}
+}
+
+// assignStmt returns an assignment of the integer value i to the variable of
+// the given name.
+func assignStmt(name string, i int) *ast.AssignStmt {
+	return &ast.AssignStmt{
+		Lhs: []ast.Expr{ast.NewIdent(name)},
+		Tok: token.ASSIGN,
+		Rhs: []ast.Expr{&ast.BasicLit{Kind: token.INT, Value: strconv.Itoa(i)}},
+	}
+}
+
+// choice returns the conditions of selecting each of n alternatives, based on
+// the integer value of the variable of the given name. The last alternative is
+// selected when none of the other alternatives are.
+func choice(name string, n int) []logic.Expr {
+	if n == 1 {
+		return []logic.Expr{logic.True}
+	}
+	var conds, last []logic.Expr
+	for i := 0; i < n-1; i++ {
+		l := logic.Eq{X: logic.Sym(name), Value: strconv.Itoa(i)}
+		conds = append(conds, l)
+		last = append(last, logic.NewNot(l))
+	}
+	if n > 0 {
+		conds = append(conds, logic.DNF(logic.NewAnd(last...)))
+	}
+	return conds
+}
+
+// condExpr returns the Go expression of the given condition, with compound
+// conditions expanded.
+func (s *structurer) condExpr(c logic.Expr) ast.Expr {
+	return boolExpr(logic.Simplify(logic.Substitute(c, s.conds)))
+}
@@ -0,0 +1,180 @@
+// ref: Kildall, Gary A. "A unified approach to global program optimization."
+// Proceedings of the 1st annual ACM SIGACT-SIGPLAN symposium on Principles of
+// programming languages. ACM, 1973.
+
+package flow
+
+import (
+	"fmt"
+
+	"github.com/graphism/exp/cfg"
+	"gonum.org/v1/gonum/graph"
+)
+
+// Fact is a dataflow fact; an element of the lattice of a dataflow problem.
+// Facts are treated as immutable values by the solvers.
+type Fact interface{}
+
+// Lattice is a join semilattice of dataflow facts of finite height.
+type Lattice interface {
+	// Bottom returns the least element of the lattice; the initial fact of each
+	// node.
+	Bottom() Fact
+	// Join returns the least upper bound of a and b.
+	Join(a, b Fact) Fact
+	// Equal reports whether a and b are equal.
+	Equal(a, b Fact) bool
+}
+
+// Direction specifies the direction of a dataflow problem.
+type Direction uint8
+
+// Dataflow directions.
+const (
+	// Forward problems propagate facts from predecessors to successors.
+	Forward Direction = iota
+	// Backward problems propagate facts from successors to predecessors.
+	Backward
+)
+
+// String returns the string representation of the direction.
+func (dir Direction) String() string {
+	switch dir {
+	case Forward:
+		return "forward"
+	case Backward:
+		return "backward"
+	default:
+		return fmt.Sprintf("Direction(%d)", uint8(dir))
+	}
+}
+
+// TransferFunc is the transfer function of a dataflow problem. It maps the fact
+// at the entry of n to the fact at its exit for forward problems, and the fact
+// at the exit of n to the fact at its entry for backward problems. Transfer
+// functions must be monotone.
+type TransferFunc func(n *cfg.Node, fact Fact) Fact
+
+// Problem is a monotone dataflow problem. Problems of either direction may be
+// solved by Solve, while SolveIntervals only supports forward problems.
+type Problem struct {
+	// Direction of the problem.
+	Dir Direction
+	// Lattice of dataflow facts.
+	Lattice Lattice
+	// Boundary fact, flowing into the entry node for forward problems, and out
+	// of the exit nodes (i.e. nodes without successors) for backward problems.
+	Boundary Fact
+	// Transfer function of the problem.
+	Transfer TransferFunc
+}
+
+// Solution is the solution of a dataflow problem; the least fixed point of its
+// dataflow equations.
+type Solution struct {
+	// Facts at the entry of each node; mapping from node ID to fact.
+	in map[int64]Fact
+	// Facts at the exit of each node; mapping from node ID to fact.
+	out map[int64]Fact
+}
+
+// newSolution returns a new solution, initializing the facts of each node of g
+// to bottom.
+func newSolution(g graph.Directed, p *Problem) *Solution {
+	s := &Solution{
+		in:  make(map[int64]Fact),
+		out: make(map[int64]Fact),
+	}
+	nodes := g.Nodes()
+	for nodes.Next() {
+		id := nodes.Node().ID()
+		s.in[id] = p.Lattice.Bottom()
+		s.out[id] = p.Lattice.Bottom()
+	}
+	return s
+}
+
+// In returns the fact at the entry of the given node.
+func (s *Solution) In(n graph.Node) Fact {
+	return s.in[n.ID()]
+}
+
+// Out returns the fact at the exit of the given node.
+func (s *Solution) Out(n graph.Node) Fact {
+	return s.out[n.ID()]
+}
+
+// Solve solves the dataflow problem p on the control flow graph g, using a
+// worklist which visits nodes in reverse post-order for forward problems, and in
+// post-order for backward problems. The depth first search order of g is
+// reinitialized.
+func Solve(g *cfg.Graph, p *Problem) *Solution {
+	cfg.InitDFSOrder(g)
+	nodes := cfg.SortByRevPost(graph.NodesOf(g.Nodes()))
+	if p.Dir == Backward {
+		nodes = cfg.SortByPost(graph.NodesOf(g.Nodes()))
+	}
+	s := newSolution(g, p)
+	pending := make(map[int64]bool)
+	for _, n := range nodes {
+		pending[n.ID()] = true
+	}
+	for len(pending) > 0 {
+		for _, n := range nodes {
+			if !pending[n.ID()] {
+				continue
+			}
+			delete(pending, n.ID())
+			if !s.update(g, p, n) {
+				continue
+			}
+			// Revisit the nodes which depend on n.
+			deps := g.From(n.ID())
+			if p.Dir == Backward {
+				deps = g.To(n.ID())
+			}
+			for deps.Next() {
+				pending[deps.Node().ID()] = true
+			}
+		}
+	}
+	return s
+}
+
+// update recomputes the facts of the node n, and reports whether the fact
+// propagated to the nodes which depend on n changed.
+func (s *Solution) update(g *cfg.Graph, p *Problem, n *cfg.Node) bool {
+	l := p.Lattice
+	id := n.ID()
+	switch p.Dir {
+	case Forward:
+		in := l.Bottom()
+		if n == g.Entry() {
+			in = l.Join(in, p.Boundary)
+		}
+		for preds := g.To(id); preds.Next(); {
+			in = l.Join(in, s.out[preds.Node().ID()])
+		}
+		s.in[id] = in
+		out := p.Transfer(n, in)
+		changed := !l.Equal(out, s.out[id])
+		s.out[id] = out
+		return changed
+	case Backward:
+		out := l.Bottom()
+		succs := g.From(id)
+		if succs.Len() == 0 {
+			out = l.Join(out, p.Boundary)
+		}
+		for succs.Next() {
+			out = l.Join(out, s.in[succs.Node().ID()])
+		}
+		s.out[id] = out
+		in := p.Transfer(n, out)
+		changed := !l.Equal(in, s.in[id])
+		s.in[id] = in
+		return changed
+	default:
+		panic(fmt.Errorf("support for dataflow direction %v not yet implemented", p.Dir))
+	}
+}
@@ -1,2 +1,2 @@
-// Package flow provides control flow analysis functions.
+// Package flow provides control flow and data flow analysis functions.
 package flow
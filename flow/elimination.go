@@ -0,0 +1,304 @@
+// ref: Allen, Frances E., and John Cocke. "A program data flow analysis
+// procedure." Communications of the ACM 19.3 (1976): 137. [1]
+//
+// [1] https://pdfs.semanticscholar.org/81b9/49a01506a09fcd7ec4faf28e2fa0ec63f1e0.pdf
+
+package flow
+
+import (
+	"fmt"
+
+	"github.com/graphism/exp/cfg"
+	"github.com/pkg/errors"
+	"gonum.org/v1/gonum/graph"
+)
+
+// SolveIntervals solves the forward dataflow problem p on the control flow
+// graph g by interval elimination, and returns the same solution as Solve.
+//
+// In the elimination phase, the intervals of each graph of the derived sequence
+// are summarized; each node of an interval is given a flow function from the
+// entry of its interval header, and each edge between intervals of the next
+// graph a flow function composed of the flow functions of the interval. Loops
+// are summarized by the closure of the flow functions of their back edges. In
+// the propagation phase, the facts of the limit graph are computed and
+// propagated back through the derived sequence, down to the nodes of g.
+//
+// Irreducible limit graphs are solved iteratively. All nodes of g must be
+// reachable from the entry node. The depth first search order of g is
+// reinitialized. An error is returned for backward problems, as the intervals
+// of g are headed by its entry node; use Solve instead. An error is also
+// returned if an interval cannot be summarized.
+func SolveIntervals(g *cfg.Graph, p *Problem) (*Solution, error) {
+	if p.Dir != Forward {
+		return nil, errors.Errorf("support for interval elimination of %v dataflow problems not yet implemented", p.Dir)
+	}
+	// Elimination phase.
+	cfg.InitDFSOrder(g)
+	ls := []*level{newLevel(g, p)}
+	for {
+		l := ls[len(ls)-1]
+		if l.g.Nodes().Len() == 1 {
+			break
+		}
+		Is := Intervals(l.g, l.g.Entry())
+		// G^n is an irreducible limit graph if collapsing its intervals leaves it
+		// unchanged.
+		if IsLimitGraph(l.g, Is) {
+			break
+		}
+		next, err := l.derive(Is)
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+		ls = append(ls, next)
+	}
+	// Propagation phase.
+	in := ls[len(ls)-1].solve()
+	for i := len(ls) - 2; i >= 0; i-- {
+		l := ls[i]
+		facts := make(map[int64]Fact)
+		for id, f := range l.paths {
+			facts[id] = f(in[l.parent[id]])
+		}
+		in = facts
+	}
+	s := newSolution(g, p)
+	for _, n := range cfg.SortByRevPost(graph.NodesOf(g.Nodes())) {
+		s.in[n.ID()] = in[n.ID()]
+		s.out[n.ID()] = p.Transfer(n, in[n.ID()])
+	}
+	return s, nil
+}
+
+// flowFunc maps the fact at the entry of a region to the fact at a given point
+// of the region.
+type flowFunc func(Fact) Fact
+
+// flowEdge is an edge of a graph in the derived sequence, identified by the IDs
+// of its source and destination nodes.
+type flowEdge struct {
+	from, to int64
+}
+
+// level is a graph of the derived sequence, summarized for interval
+// elimination.
+type level struct {
+	// Dataflow problem.
+	p *Problem
+	// Graph of the derived sequence.
+	g *cfg.Graph
+	// Flow function of each edge, from the entry of its source node to the fact
+	// flowing along the edge.
+	edges map[flowEdge]flowFunc
+	// Flow function of each node, from the entry of its interval header to the
+	// entry of the node; or nil for the limit graph.
+	paths map[int64]flowFunc
+	// Node of the next graph of the derived sequence collapsing the interval of
+	// each node; mapping from node ID to node ID.
+	parent map[int64]int64
+}
+
+// newLevel returns the first graph of the derived sequence, which is g.
+func newLevel(g *cfg.Graph, p *Problem) *level {
+	l := &level{
+		p:     p,
+		g:     g,
+		edges: make(map[flowEdge]flowFunc),
+	}
+	nodes := g.Nodes()
+	for nodes.Next() {
+		n := nodes.Node().(*cfg.Node)
+		transfer := p.memo(func(x Fact) Fact {
+			return p.Transfer(n, x)
+		})
+		for succs := g.From(n.ID()); succs.Next(); {
+			l.edges[flowEdge{from: n.ID(), to: succs.Node().ID()}] = transfer
+		}
+	}
+	return l
+}
+
+// derive summarizes the given intervals of the graph, and returns the next graph
+// of the derived sequence; collapsing each interval into a single node.
+func (l *level) derive(Is []*Interval) (*level, error) {
+	next := &level{
+		p:     l.p,
+		g:     cfg.NewGraph(),
+		edges: make(map[flowEdge]flowFunc),
+	}
+	l.paths = make(map[int64]flowFunc)
+	l.parent = make(map[int64]int64)
+	for i, I := range Is {
+		n := next.g.NewNodeWithName(fmt.Sprintf("I%d", i+1))
+		next.g.AddNode(n)
+		if I.Head == l.g.Entry() {
+			next.g.SetEntry(n)
+		}
+		for nodes := I.Nodes(); nodes.Next(); {
+			l.parent[nodes.Node().ID()] = n.ID()
+		}
+		if err := l.summarize(I); err != nil {
+			return nil, errors.WithStack(err)
+		}
+	}
+	// Compose the flow functions of edges between intervals.
+	exits := make(map[flowEdge][]flowFunc)
+	var order []flowEdge
+	for _, n := range cfg.SortByRevPost(graph.NodesOf(l.g.Nodes())) {
+		for _, succ := range cfg.SortByRevPost(graph.NodesOf(l.g.From(n.ID()))) {
+			from, to := l.parent[n.ID()], l.parent[succ.ID()]
+			if from == to {
+				continue
+			}
+			e := flowEdge{from: from, to: to}
+			if _, ok := exits[e]; !ok {
+				order = append(order, e)
+			}
+			path, f := l.paths[n.ID()], l.edges[flowEdge{from: n.ID(), to: succ.ID()}]
+			exits[e] = append(exits[e], func(x Fact) Fact {
+				return f(path(x))
+			})
+		}
+	}
+	for _, e := range order {
+		next.g.SetEdge(next.g.NewEdge(next.g.Node(e.from), next.g.Node(e.to)))
+		next.edges[e] = l.p.memo(l.p.join(exits[e]))
+	}
+	cfg.InitDFSOrder(next.g)
+	return next, nil
+}
+
+// summarize computes the flow functions of the nodes of the interval I, from
+// the entry of its header.
+func (l *level) summarize(I *Interval) error {
+	// Nodes of an interval are ordered topologically by reverse post-order,
+	// ignoring back edges to the header.
+	paths := make(map[int64]flowFunc)
+	var nodes []*cfg.Node
+	for it := I.Nodes(); it.Next(); {
+		nodes = append(nodes, it.Node().(*cfg.Node))
+	}
+	head := I.Head.ID()
+	if nodes[0].ID() != head {
+		return errors.Errorf("invalid first node %v of interval; expected header %v", nodes[0], I.Head)
+	}
+	paths[head] = func(x Fact) Fact {
+		return x
+	}
+	for _, n := range nodes[1:] {
+		fs, err := l.flowsTo(I, n, paths)
+		if err != nil {
+			return errors.WithStack(err)
+		}
+		paths[n.ID()] = l.p.memo(l.p.join(fs))
+	}
+	back, err := l.flowsTo(I, nodes[0], paths)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	// Close the loop of the interval, if any, at the header.
+	closure := paths[head]
+	if len(back) > 0 {
+		loop := l.p.join(back)
+		closure = l.p.memo(func(x Fact) Fact {
+			y := x
+			for {
+				z := l.p.Lattice.Join(x, loop(y))
+				if l.p.Lattice.Equal(y, z) {
+					return y
+				}
+				y = z
+			}
+		})
+	}
+	for _, n := range nodes {
+		path := paths[n.ID()]
+		if n.ID() == head {
+			l.paths[n.ID()] = closure
+			continue
+		}
+		l.paths[n.ID()] = l.p.memo(func(x Fact) Fact {
+			return path(closure(x))
+		})
+	}
+	return nil
+}
+
+// flowsTo returns the flow functions of the edges to n from its predecessors
+// within the interval I, from the entry of the interval header. An error is
+// returned if a predecessor other than the header has not yet been summarized;
+// i.e. if I contains a cycle not passing through its header.
+func (l *level) flowsTo(I *Interval, n *cfg.Node, paths map[int64]flowFunc) ([]flowFunc, error) {
+	var fs []flowFunc
+	for _, pred := range cfg.SortByRevPost(graph.NodesOf(l.g.To(n.ID()))) {
+		if I.Node(pred.ID()) == nil {
+			// Entry edge of the interval.
+			continue
+		}
+		path, f := paths[pred.ID()], l.edges[flowEdge{from: pred.ID(), to: n.ID()}]
+		if path == nil {
+			return nil, errors.Errorf("invalid predecessor %v of node %v; not yet summarized", pred, n)
+		}
+		fs = append(fs, func(x Fact) Fact {
+			return f(path(x))
+		})
+	}
+	return fs, nil
+}
+
+// solve solves the dataflow equations of the limit graph iteratively, and
+// returns the fact at the entry of each node.
+func (l *level) solve() map[int64]Fact {
+	lat := l.p.Lattice
+	nodes := cfg.SortByRevPost(graph.NodesOf(l.g.Nodes()))
+	in := make(map[int64]Fact)
+	for _, n := range nodes {
+		in[n.ID()] = lat.Bottom()
+	}
+	for changed := true; changed; {
+		changed = false
+		for _, n := range nodes {
+			x := lat.Bottom()
+			if n == l.g.Entry() {
+				x = lat.Join(x, l.p.Boundary)
+			}
+			for preds := l.g.To(n.ID()); preds.Next(); {
+				pred := preds.Node().ID()
+				x = lat.Join(x, l.edges[flowEdge{from: pred, to: n.ID()}](in[pred]))
+			}
+			if !lat.Equal(x, in[n.ID()]) {
+				in[n.ID()] = x
+				changed = true
+			}
+		}
+	}
+	return in
+}
+
+// join returns the flow function joining the facts of the given flow
+// functions.
+func (p *Problem) join(fs []flowFunc) flowFunc {
+	return func(x Fact) Fact {
+		y := p.Lattice.Bottom()
+		for _, f := range fs {
+			y = p.Lattice.Join(y, f(x))
+		}
+		return y
+	}
+}
+
+// memo returns a flow function which caches the fact of the most recent
+// evaluation of f; thus evaluating the flow functions of an interval for a
+// given fact in linear time.
+func (p *Problem) memo(f flowFunc) flowFunc {
+	var prev, fact Fact
+	valid := false
+	return func(x Fact) Fact {
+		if valid && p.Lattice.Equal(x, prev) {
+			return fact
+		}
+		prev, fact, valid = x, f(x), true
+		return fact
+	}
+}
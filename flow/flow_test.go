@@ -4,9 +4,15 @@ import (
 	"fmt"
 	"reflect"
 	"sort"
+	"strings"
 	"testing"
 
 	"github.com/graphism/exp/cfg"
+	"github.com/llir/llvm/ir"
+	"github.com/llir/llvm/ir/constant"
+	"github.com/llir/llvm/ir/types"
+	"github.com/llir/llvm/ir/value"
+	"gonum.org/v1/gonum/graph"
 )
 
 func TestIntervals(t *testing.T) {
@@ -22,6 +28,17 @@ func TestIntervals(t *testing.T) {
 				[]string{"B13", "B14", "B15"},
 			},
 		},
+		// A node with a self-loop is the header of its own interval, as the
+		// self-loop is a closed path not containing any other header.
+		{
+			path: "testdata/self_loop.dot",
+			want: [][]string{
+				[]string{"n0"},
+				[]string{"n1"},
+				[]string{"n2"},
+				[]string{"n3"},
+			},
+		},
 	}
 	for _, gold := range golden {
 		// Parse input.
@@ -30,7 +47,8 @@ func TestIntervals(t *testing.T) {
 			t.Errorf("%q; unable to parse file; %v", gold.path, err)
 			continue
 		}
-		// Locate intervals.
+		// Locate intervals, processing header nodes in reverse post-order.
+		cfg.InitDFSOrder(in)
 		intervals := Intervals(in, in.Entry())
 		if len(intervals) != len(gold.want) {
 			t.Errorf("%q: number of intervals mismatch; expected %d, got %d", gold.path, len(gold.want), len(intervals))
@@ -59,3 +77,663 @@ func TestIntervals(t *testing.T) {
 		}
 	}
 }
+
+func TestIsLimitGraph(t *testing.T) {
+	golden := []struct {
+		in   string
+		want bool
+	}{
+		{in: "digraph { A [label=entry]; A -> B; B -> A; }", want: false},
+		// Irreducible.
+		{in: "digraph { A [label=entry]; A -> B; A -> C; B -> C; C -> B; }", want: true},
+		// Irreducible, with a self-loop removed by collapsing its interval.
+		{in: "digraph { A [label=entry]; A -> B; A -> C; B -> C; C -> B; B -> B; }", want: false},
+	}
+	for _, gold := range golden {
+		g, err := cfg.ParseString(gold.in)
+		if err != nil {
+			t.Errorf("%q; unable to parse graph; %v", gold.in, err)
+			continue
+		}
+		cfg.InitDFSOrder(g)
+		if got := IsLimitGraph(g, Intervals(g, g.Entry())); got != gold.want {
+			t.Errorf("%q; limit graph mismatch; expected %v, got %v", gold.in, gold.want, got)
+		}
+	}
+}
+
+func TestProgramStructureTree(t *testing.T) {
+	golden := []struct {
+		path string
+		want string
+	}{
+		{
+			path: "testdata/sese.dot",
+			want: `
+-, -: A B C E F G H D
+	-, A->B: A
+	A->B, E->F: B C E D
+		B->C, C->E: C
+		B->D, D->E: D
+	E->F, G->H: F G
+	G->H, -: H`,
+		},
+		{
+			path: "testdata/sample.dot",
+			want: `
+-, -: B1 B2 B3 B5 B6 B7 B8 B9 B10 B11 B12 B13 B14 B15 B4
+	-, B5->B6: B1 B2 B3 B5 B4
+		B2->B3, B3->B5: B3
+		B2->B4, B4->B5: B4
+	B5->B6, B6->B7: B6 B12 B13 B14 B15
+		B6->B12, B12->B13: B12
+		B12->B13, B14->B15: B13 B14
+		B14->B15, B15->B6: B15
+	B6->B7, B10->B11: B7 B8 B9 B10
+	B10->B11, -: B11`,
+		},
+	}
+	for _, gold := range golden {
+		in, err := cfg.ParseFile(gold.path)
+		if err != nil {
+			t.Errorf("%q; unable to parse file; %v", gold.path, err)
+			continue
+		}
+		root := ProgramStructureTree(in, in.Entry())
+		buf := &strings.Builder{}
+		var dump func(r *Region, indent string)
+		dump = func(r *Region, indent string) {
+			var names []string
+			for _, n := range r.Nodes {
+				names = append(names, n.(*cfg.Node).DOTID())
+			}
+			fmt.Fprintf(buf, "\n%s%s, %s: %s", indent, edgeName(r.Entry), edgeName(r.Exit), strings.Join(names, " "))
+			for _, child := range r.Children {
+				dump(child, indent+"\t")
+			}
+		}
+		dump(root, "")
+		if got := buf.String(); got != gold.want {
+			t.Errorf("%q; output mismatch; expected `%s`, got `%s`", gold.path, gold.want, got)
+		}
+	}
+}
+
+// edgeName returns the name of the given edge, or "-" if nil.
+func edgeName(e graph.Edge) string {
+	if e == nil {
+		return "-"
+	}
+	return fmt.Sprintf("%s->%s", e.From().(*cfg.Node).DOTID(), e.To().(*cfg.Node).DOTID())
+}
+
+func TestSmallest(t *testing.T) {
+	golden := []struct {
+		path  string
+		nodes []string
+		// Entry and exit edges of the smallest region.
+		want string
+	}{
+		{path: "testdata/sese.dot", nodes: []string{"C"}, want: "B->C, C->E"},
+		{path: "testdata/sese.dot", nodes: []string{"C", "D"}, want: "A->B, E->F"},
+		{path: "testdata/sese.dot", nodes: []string{"A", "H"}, want: "-, -"},
+	}
+	for _, gold := range golden {
+		in, err := cfg.ParseFile(gold.path)
+		if err != nil {
+			t.Errorf("%q; unable to parse file; %v", gold.path, err)
+			continue
+		}
+		var nodes []graph.Node
+		for _, name := range gold.nodes {
+			n, ok := in.NodeWithName(name)
+			if !ok {
+				t.Fatalf("%q; unable to locate node %q", gold.path, name)
+			}
+			nodes = append(nodes, n)
+		}
+		root := ProgramStructureTree(in, in.Entry())
+		r, ok := root.Smallest(nodes...)
+		if !ok {
+			t.Errorf("%q; unable to locate region of nodes %q", gold.path, gold.nodes)
+			continue
+		}
+		if got := fmt.Sprintf("%s, %s", edgeName(r.Entry), edgeName(r.Exit)); got != gold.want {
+			t.Errorf("%q; region mismatch of nodes %q; expected %q, got %q", gold.path, gold.nodes, gold.want, got)
+		}
+	}
+}
+
+func TestReduce(t *testing.T) {
+	golden := []struct {
+		path  string
+		steps string
+		limit string
+	}{
+		{
+			path:  "testdata/sese.dot",
+			steps: "T2(B, A) T2(C, A) T2(G, F) T1(F) T2(F, E) T2(H, E) T2(D, A) T2(E, A)",
+			limit: "A",
+		},
+		{
+			path:  "testdata/improper.dot",
+			steps: "T2(D, C)",
+			limit: "A B C",
+		},
+	}
+	for _, gold := range golden {
+		in, err := cfg.ParseFile(gold.path)
+		if err != nil {
+			t.Errorf("%q; unable to parse file; %v", gold.path, err)
+			continue
+		}
+		steps, limit := Reduce(in, in.Entry())
+		var ss []string
+		for _, step := range steps {
+			if step.Transform == T1 {
+				ss = append(ss, fmt.Sprintf("T1(%s)", step.Node.(*cfg.Node).DOTID()))
+			} else {
+				ss = append(ss, fmt.Sprintf("T2(%s, %s)", step.Node.(*cfg.Node).DOTID(), step.Pred.(*cfg.Node).DOTID()))
+			}
+		}
+		if got := strings.Join(ss, " "); got != gold.steps {
+			t.Errorf("%q; reduction sequence mismatch; expected `%s`, got `%s`", gold.path, gold.steps, got)
+		}
+		var names []string
+		for _, n := range limit {
+			names = append(names, n.(*cfg.Node).DOTID())
+		}
+		if got := strings.Join(names, " "); got != gold.limit {
+			t.Errorf("%q; limit graph mismatch; expected `%s`, got `%s`", gold.path, gold.limit, got)
+		}
+		if got, want := IsReducible(in, in.Entry()), len(limit) == 1; got != want {
+			t.Errorf("%q; reducibility mismatch; expected %v, got %v", gold.path, want, got)
+		}
+	}
+}
+
+func TestSolve(t *testing.T) {
+	golden := []struct {
+		path string
+		dir  Direction
+		want string
+	}{
+		// Nodes on paths from the entry node to the entry of each node.
+		{
+			path: "testdata/sese.dot",
+			dir:  Forward,
+			want: `
+A: 
+B: A
+C: A B
+D: A B
+E: A B C D
+F: A B C D E F G
+G: A B C D E F G
+H: A B C D E F G`,
+		},
+		// Nodes reachable from each node.
+		{
+			path: "testdata/sese.dot",
+			dir:  Backward,
+			want: `
+A: A B C D E F G H
+B: B C D E F G H
+C: C E F G H
+D: D E F G H
+E: E F G H
+F: F G H
+G: F G H
+H: H`,
+		},
+	}
+	for _, gold := range golden {
+		in, err := cfg.ParseFile(gold.path)
+		if err != nil {
+			t.Errorf("%q; unable to parse file; %v", gold.path, err)
+			continue
+		}
+		s := Solve(in, nodeSetProblem(gold.dir))
+		buf := &strings.Builder{}
+		for _, n := range sortByDOTID(in) {
+			fmt.Fprintf(buf, "\n%s: %s", n.DOTID(), s.In(n))
+		}
+		if got := buf.String(); got != gold.want {
+			t.Errorf("%q; %v output mismatch; expected `%s`, got `%s`", gold.path, gold.dir, gold.want, got)
+		}
+	}
+}
+
+func TestSolveIntervals(t *testing.T) {
+	golden := []struct {
+		path string
+	}{
+		{path: "testdata/sample.dot"},
+		{path: "testdata/sese.dot"},
+		// Self-loop on a node which is not the header of an enclosing loop.
+		{path: "testdata/self_loop.dot"},
+		// Irreducible.
+		{path: "testdata/improper.dot"},
+	}
+	for _, gold := range golden {
+		in, err := cfg.ParseFile(gold.path)
+		if err != nil {
+			t.Errorf("%q; unable to parse file; %v", gold.path, err)
+			continue
+		}
+		p := nodeSetProblem(Forward)
+		want := Solve(in, p)
+		got, err := SolveIntervals(in, p)
+		if err != nil {
+			t.Errorf("%q; unable to solve dataflow problem; %v", gold.path, err)
+			continue
+		}
+		for _, n := range sortByDOTID(in) {
+			if want.In(n) != got.In(n) || want.Out(n) != got.Out(n) {
+				t.Errorf("%q; solution mismatch of node %q; expected `%v, %v`, got `%v, %v`", gold.path, n.DOTID(), want.In(n), want.Out(n), got.In(n), got.Out(n))
+			}
+		}
+	}
+}
+
+func TestSolveIntervalsBackward(t *testing.T) {
+	in, err := cfg.ParseFile("testdata/sample.dot")
+	if err != nil {
+		t.Fatalf("unable to parse file; %v", err)
+	}
+	if _, err := SolveIntervals(in, nodeSetProblem(Backward)); err == nil {
+		t.Errorf("expected error for backward dataflow problem, got nil")
+	}
+}
+
+// nodeSetProblem returns a dataflow problem of the given direction, adding each
+// node to the set of nodes propagated through it.
+func nodeSetProblem(dir Direction) *Problem {
+	return &Problem{
+		Dir:      dir,
+		Lattice:  nodeSet{},
+		Boundary: "",
+		Transfer: func(n *cfg.Node, fact Fact) Fact {
+			return nodeSet{}.Join(fact, n.DOTID())
+		},
+	}
+}
+
+// nodeSet is a lattice of node sets, represented as space-separated sorted
+// node names.
+type nodeSet struct{}
+
+func (nodeSet) Bottom() Fact {
+	return ""
+}
+
+func (nodeSet) Join(a, b Fact) Fact {
+	m := make(map[string]bool)
+	for _, name := range strings.Fields(a.(string) + " " + b.(string)) {
+		m[name] = true
+	}
+	var names []string
+	for name := range m {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return strings.Join(names, " ")
+}
+
+func (nodeSet) Equal(a, b Fact) bool {
+	return a == b
+}
+
+// sortByDOTID returns the nodes of g sorted by DOT ID.
+func sortByDOTID(g *cfg.Graph) []*cfg.Node {
+	var nodes []*cfg.Node
+	for it := g.Nodes(); it.Next(); {
+		nodes = append(nodes, it.Node().(*cfg.Node))
+	}
+	sort.Slice(nodes, func(i, j int) bool {
+		return nodes[i].DOTID() < nodes[j].DOTID()
+	})
+	return nodes
+}
+
+// newLoopFunc returns a function with a loop and a phi instruction, and its
+// control flow graph.
+//
+//    define i32 @f(i32 %n, i1 %c) {
+//    entry:
+//       br label %loop
+//    loop:
+//       %i = phi i32 [ %n, %entry ], [ %j, %body ]
+//       br i1 %c, label %body, label %exit
+//    body:
+//       %j = add i32 %i, 1
+//       br label %loop
+//    exit:
+//       ret i32 %i
+//    }
+func newLoopFunc() (*cfg.Graph, *ir.Func) {
+	n, c := ir.NewParam("n", types.I32), ir.NewParam("c", types.I1)
+	f := ir.NewFunc("f", types.I32, n, c)
+	entry := f.NewBlock("entry")
+	loop := f.NewBlock("loop")
+	body := f.NewBlock("body")
+	exit := f.NewBlock("exit")
+	entry.NewBr(loop)
+	i := loop.NewPhi(ir.NewIncoming(n, entry))
+	i.SetName("i")
+	loop.NewCondBr(c, body, exit)
+	j := body.NewAdd(i, constant.NewInt(types.I32, 1))
+	j.SetName("j")
+	body.NewBr(loop)
+	i.Incs = append(i.Incs, ir.NewIncoming(j, body))
+	exit.NewRet(i)
+	return cfg.NewGraphFromFunc(f), f
+}
+
+func TestLiveVars(t *testing.T) {
+	g, f := newLoopFunc()
+	lv := LiveVars(g, f)
+	golden := []struct {
+		block   string
+		in, out string
+	}{
+		// The phi operand %n is live at the exit of entry only.
+		{block: "entry", in: "n c", out: "n c"},
+		// %i is defined at the entry of loop, thus not live at its entry.
+		{block: "loop", in: "c", out: "c i"},
+		// The phi operand %j is live at the exit of body, but not at the entry of
+		// loop.
+		{block: "body", in: "c i", out: "c j"},
+		{block: "exit", in: "i", out: ""},
+	}
+	blocks := blocksByName(f)
+	for _, gold := range golden {
+		block := blocks[gold.block]
+		if got := namesOf(lv.In(block)); got != gold.in {
+			t.Errorf("%q; live-in mismatch; expected `%s`, got `%s`", gold.block, gold.in, got)
+		}
+		if got := namesOf(lv.Out(block)); got != gold.out {
+			t.Errorf("%q; live-out mismatch; expected `%s`, got `%s`", gold.block, gold.out, got)
+		}
+	}
+}
+
+func TestReachDefs(t *testing.T) {
+	g, f := newLoopFunc()
+	rd := ReachDefs(g, f)
+	golden := []struct {
+		block   string
+		in, out string
+	}{
+		{block: "entry", in: "n c", out: "n c"},
+		// %j reaches the entry of loop through the back edge from body.
+		{block: "loop", in: "n c i j", out: "n c i j"},
+		{block: "body", in: "n c i j", out: "n c i j"},
+		{block: "exit", in: "n c i j", out: "n c i j"},
+	}
+	blocks := blocksByName(f)
+	for _, gold := range golden {
+		block := blocks[gold.block]
+		if got := namesOf(rd.In(block)); got != gold.in {
+			t.Errorf("%q; reaching definitions mismatch at entry; expected `%s`, got `%s`", gold.block, gold.in, got)
+		}
+		if got := namesOf(rd.Out(block)); got != gold.out {
+			t.Errorf("%q; reaching definitions mismatch at exit; expected `%s`, got `%s`", gold.block, gold.out, got)
+		}
+	}
+}
+
+func TestDefUseChains(t *testing.T) {
+	_, f := newLoopFunc()
+	du := DefUseChains(f)
+	n, c := f.Params[0], f.Params[1]
+	loop, body, exit := f.Blocks[1], f.Blocks[2], f.Blocks[3]
+	i := loop.Insts[0].(*ir.InstPhi)
+	j := body.Insts[0].(*ir.InstAdd)
+	// Use-def links of the phi operands, in operand order.
+	if got, want := namesOf(du.Defs(i)), "n j"; got != want {
+		t.Errorf("%q; use-def mismatch; expected `%s`, got `%s`", i.Ident(), want, got)
+	}
+	golden := []struct {
+		def  value.Named
+		want []*Use
+	}{
+		// Phi operands are attributed to the incoming edges.
+		{def: n, want: []*Use{{User: i, Block: loop, Pred: f.Blocks[0]}}},
+		{def: j, want: []*Use{{User: i, Block: loop, Pred: body}}},
+		{def: c, want: []*Use{{User: loop.Term.(value.User), Block: loop}}},
+		{def: i, want: []*Use{{User: j, Block: body}, {User: exit.Term.(value.User), Block: exit}}},
+	}
+	for _, gold := range golden {
+		if got := du.Uses(gold.def); !reflect.DeepEqual(got, gold.want) {
+			t.Errorf("%q; def-use mismatch; expected %v, got %v", gold.def.Ident(), gold.want, got)
+		}
+	}
+}
+
+// namesOf returns the space-separated names of the given values.
+func namesOf(vs []value.Named) string {
+	var names []string
+	for _, v := range vs {
+		names = append(names, v.Name())
+	}
+	return strings.Join(names, " ")
+}
+
+func TestDestroySSA(t *testing.T) {
+	golden := []struct {
+		name string
+		f    func() *ir.Func
+		want string
+	}{
+		// The parallel copies of the back edge form a cycle, broken by a
+		// temporary variable.
+		{
+			name: "swap",
+			f:    newSwapFunc,
+			want: `
+x: x a
+y: y b
+c: c
+tmp1:
+latch exit: tmp1 = x
+latch exit: x = y
+latch exit: y = tmp1`,
+		},
+		// %i is live after the definition of %j, thus not coalesced.
+		{
+			name: "lost_copy",
+			f:    newLostCopyFunc,
+			want: `
+c: c
+i: i
+j: j
+entry exit: i = 0
+latch exit: i = j`,
+		},
+		// The copy of the critical back edge is placed in the split node.
+		{
+			name: "critical_edge",
+			f:    newCriticalEdgeFunc,
+			want: `
+c: c
+i: i
+j: j
+entry exit: i = 0
+loop_loop exit: i = j`,
+		},
+		// Non-interfering values are coalesced, leaving no copies.
+		{
+			name: "coalesce",
+			f:    newCoalesceFunc,
+			want: `
+p: p
+c: c
+x: x y z`,
+		},
+	}
+	for _, gold := range golden {
+		f := gold.f()
+		g := cfg.NewGraphFromFunc(f)
+		o := DestroySSA(g, f)
+		buf := &strings.Builder{}
+		for _, x := range o.Vars {
+			fmt.Fprintf(buf, "\n%s:", x.Name)
+			for _, v := range x.Values {
+				fmt.Fprintf(buf, " %s", v.Name())
+			}
+		}
+		for _, n := range sortByDOTID(g) {
+			for _, c := range o.EntryCopies(n) {
+				fmt.Fprintf(buf, "\n%s entry: %s", n.DOTID(), copyString(c))
+			}
+			for _, c := range o.ExitCopies(n) {
+				fmt.Fprintf(buf, "\n%s exit: %s", n.DOTID(), copyString(c))
+			}
+		}
+		if got := buf.String(); got != gold.want {
+			t.Errorf("%q; output mismatch; expected `%s`, got `%s`", gold.name, gold.want, got)
+		}
+	}
+}
+
+// newSwapFunc returns a function exhibiting the swap problem; the phi
+// instructions of loop read each other's values on the back edge.
+//
+//    define i32 @f(i32 %x, i32 %y, i1 %c) {
+//    entry:
+//       br label %loop
+//    loop:
+//       %a = phi i32 [ %x, %entry ], [ %b, %latch ]
+//       %b = phi i32 [ %y, %entry ], [ %a, %latch ]
+//       br i1 %c, label %latch, label %exit
+//    latch:
+//       br label %loop
+//    exit:
+//       ret i32 %a
+//    }
+func newSwapFunc() *ir.Func {
+	x, y, c := ir.NewParam("x", types.I32), ir.NewParam("y", types.I32), ir.NewParam("c", types.I1)
+	f := ir.NewFunc("f", types.I32, x, y, c)
+	entry := f.NewBlock("entry")
+	loop := f.NewBlock("loop")
+	latch := f.NewBlock("latch")
+	exit := f.NewBlock("exit")
+	entry.NewBr(loop)
+	a := loop.NewPhi(ir.NewIncoming(x, entry))
+	a.SetName("a")
+	b := loop.NewPhi(ir.NewIncoming(y, entry), ir.NewIncoming(a, latch))
+	b.SetName("b")
+	a.Incs = append(a.Incs, ir.NewIncoming(b, latch))
+	loop.NewCondBr(c, latch, exit)
+	latch.NewBr(loop)
+	exit.NewRet(a)
+	return f
+}
+
+// newLostCopyFunc returns a function exhibiting the lost-copy problem; the
+// value of the phi instruction is used after the loop, while the phi operand
+// of the back edge is live.
+//
+//    define i32 @f(i1 %c) {
+//    entry:
+//       br label %loop
+//    loop:
+//       %i = phi i32 [ 0, %entry ], [ %j, %latch ]
+//       %j = add i32 %i, 1
+//       br i1 %c, label %latch, label %exit
+//    latch:
+//       br label %loop
+//    exit:
+//       ret i32 %i
+//    }
+func newLostCopyFunc() *ir.Func {
+	c := ir.NewParam("c", types.I1)
+	f := ir.NewFunc("f", types.I32, c)
+	entry := f.NewBlock("entry")
+	loop := f.NewBlock("loop")
+	latch := f.NewBlock("latch")
+	exit := f.NewBlock("exit")
+	entry.NewBr(loop)
+	i := loop.NewPhi(ir.NewIncoming(constant.NewInt(types.I32, 0), entry))
+	i.SetName("i")
+	j := loop.NewAdd(i, constant.NewInt(types.I32, 1))
+	j.SetName("j")
+	i.Incs = append(i.Incs, ir.NewIncoming(j, latch))
+	loop.NewCondBr(c, latch, exit)
+	latch.NewBr(loop)
+	exit.NewRet(i)
+	return f
+}
+
+// newCriticalEdgeFunc returns a function with a phi instruction on a critical
+// edge; the back edge of loop, which has several successors and several
+// predecessors.
+//
+//    define i32 @f(i1 %c) {
+//    entry:
+//       br label %loop
+//    loop:
+//       %i = phi i32 [ 0, %entry ], [ %j, %loop ]
+//       %j = add i32 %i, 1
+//       br i1 %c, label %loop, label %exit
+//    exit:
+//       ret i32 %i
+//    }
+func newCriticalEdgeFunc() *ir.Func {
+	c := ir.NewParam("c", types.I1)
+	f := ir.NewFunc("f", types.I32, c)
+	entry := f.NewBlock("entry")
+	loop := f.NewBlock("loop")
+	exit := f.NewBlock("exit")
+	entry.NewBr(loop)
+	i := loop.NewPhi(ir.NewIncoming(constant.NewInt(types.I32, 0), entry))
+	i.SetName("i")
+	j := loop.NewAdd(i, constant.NewInt(types.I32, 1))
+	j.SetName("j")
+	i.Incs = append(i.Incs, ir.NewIncoming(j, loop))
+	loop.NewCondBr(c, loop, exit)
+	exit.NewRet(i)
+	return f
+}
+
+// newCoalesceFunc returns a function with two non-interfering values joined by
+// a phi instruction.
+//
+//    define i32 @f(i32 %p, i1 %c) {
+//    entry:
+//       br i1 %c, label %then, label %else
+//    then:
+//       %x = add i32 %p, 1
+//       br label %join
+//    else:
+//       %y = add i32 %p, 2
+//       br label %join
+//    join:
+//       %z = phi i32 [ %x, %then ], [ %y, %else ]
+//       ret i32 %z
+//    }
+func newCoalesceFunc() *ir.Func {
+	p, c := ir.NewParam("p", types.I32), ir.NewParam("c", types.I1)
+	f := ir.NewFunc("f", types.I32, p, c)
+	entry := f.NewBlock("entry")
+	then := f.NewBlock("then")
+	els := f.NewBlock("else")
+	join := f.NewBlock("join")
+	entry.NewCondBr(c, then, els)
+	x := then.NewAdd(p, constant.NewInt(types.I32, 1))
+	x.SetName("x")
+	then.NewBr(join)
+	y := els.NewAdd(p, constant.NewInt(types.I32, 2))
+	y.SetName("y")
+	els.NewBr(join)
+	z := join.NewPhi(ir.NewIncoming(x, then), ir.NewIncoming(y, els))
+	z.SetName("z")
+	join.NewRet(z)
+	return f
+}
+
+// copyString returns the string representation of the given copy.
+func copyString(c *Copy) string {
+	return fmt.Sprintf("%s = %s", c.Dst.Name, strings.TrimPrefix(c.Src.Ident(), "%"))
+}
@@ -62,6 +62,23 @@ func Intervals(g graph.Directed, entry graph.Node) []*Interval {
 	return intervals
 }
 
+// IsLimitGraph reports whether g is the limit graph of its derived sequence,
+// based on the intervals Is of g; i.e. whether collapsing each interval into a
+// single node leaves g unchanged. This is the case if each interval consists of
+// a single node without a self-loop, as collapsing an interval removes the
+// edges between its nodes.
+func IsLimitGraph(g graph.Directed, Is []*Interval) bool {
+	if len(Is) != g.Nodes().Len() {
+		return false
+	}
+	for _, I := range Is {
+		if g.HasEdgeFromTo(I.Head.ID(), I.Head.ID()) {
+			return false
+		}
+	}
+	return true
+}
+
 func find2_2(g graph.Directed, entry graph.Node, I *Interval) (graph.Node, bool) {
 	// 2.2. Add to I(h) any node all of whose immediate predecessors are
 	// already in I(h).
@@ -0,0 +1,524 @@
+// ref: Johnson, Richard, David Pearson, and Keshav Pingali. "The program
+// structure tree: Computing control regions in linear time." ACM SIGPLAN
+// Notices. Vol. 29. No. 6. ACM, 1994 [1].
+//
+// [1]: https://www.cs.utexas.edu/users/pingali/CS380C/2010/papers/johnson94program.pdf
+
+package flow
+
+import (
+	"sort"
+
+	"gonum.org/v1/gonum/graph"
+)
+
+// Region is a canonical single-entry single-exit (SESE) region of a control
+// flow graph; i.e. a region entered through a single entry edge and exited
+// through a single exit edge, which does not overlap any other SESE region
+// except by nesting.
+type Region struct {
+	// Entry edge of the region; or nil if the region is entered at the entry of
+	// the graph.
+	Entry graph.Edge
+	// Exit edge of the region; or nil if the region is exited by leaving the
+	// graph.
+	Exit graph.Edge
+	// Nodes of the region, including the nodes of nested regions, in depth
+	// first order.
+	Nodes []graph.Node
+	// Parent region in the program structure tree; or nil if root.
+	Parent *Region
+	// Regions nested immediately within the region, in depth first order.
+	Children []*Region
+	// nodes tracks the nodes contained within the region; mapping from node ID
+	// to node.
+	nodes map[int64]graph.Node
+	// Depth first order of the first node of the region.
+	order int
+}
+
+// Contains reports whether the region contains the given node.
+func (r *Region) Contains(n graph.Node) bool {
+	_, ok := r.nodes[n.ID()]
+	return ok
+}
+
+// Smallest returns the smallest region of the program structure tree rooted at
+// r which contains all the given nodes, and a boolean variable indicating
+// success.
+func (r *Region) Smallest(nodes ...graph.Node) (*Region, bool) {
+	for _, n := range nodes {
+		if !r.Contains(n) {
+			return nil, false
+		}
+	}
+	for _, child := range r.Children {
+		if s, ok := child.Smallest(nodes...); ok {
+			return s, true
+		}
+	}
+	return r, true
+}
+
+// ProgramStructureTree returns the program structure tree of the given graph,
+// based on the entry node. The root of the tree is a region containing the
+// nodes reachable from entry, and the remaining regions of the tree are the
+// canonical SESE regions of the graph.
+//
+// The canonical SESE regions are located based on the cycle equivalence of
+// edges; two edges are cycle equivalent if every cycle containing one of the
+// edges contains the other. To make the graph strongly connected, a virtual
+// edge from each exit node (and from a node of each endless loop) to a virtual
+// end node is added, together with a virtual edge from the end node through a
+// virtual start node to the entry node.
+func ProgramStructureTree(g graph.Directed, entry graph.Node) *Region {
+	s := newSESE(g, entry)
+	s.cycleEquiv()
+	return s.tree()
+}
+
+// sese tracks the state of SESE region detection.
+type sese struct {
+	// Nodes of the graph reachable from entry, indexed by node index. The
+	// virtual start and end nodes have no corresponding graph node.
+	nodes []graph.Node
+	// Index of the virtual start node.
+	start int
+	// Index of the virtual end node.
+	end int
+	// Edges of the augmented graph.
+	edges []*seseEdge
+	// Outgoing edges of each node, indexed by node index.
+	succs [][]int
+	// Depth first order of each node, indexed by node index.
+	pre []int
+}
+
+// seseEdge is an edge of the augmented graph.
+type seseEdge struct {
+	// Source and target node indices.
+	from, to int
+	// Corresponding graph edge; or nil if virtual.
+	e graph.Edge
+	// Cycle equivalence class.
+	class int
+	// Order in which the edge is traversed by depth first search.
+	order int
+}
+
+// newSESE returns the augmented graph of g, based on the entry node.
+func newSESE(g graph.Directed, entry graph.Node) *sese {
+	s := &sese{}
+	index := make(map[int64]int)
+	var walk func(n graph.Node)
+	walk = func(n graph.Node) {
+		index[n.ID()] = len(s.nodes)
+		s.nodes = append(s.nodes, n)
+		for _, succ := range sortByID(graph.NodesOf(g.From(n.ID()))) {
+			if _, ok := index[succ.ID()]; !ok {
+				walk(succ)
+			}
+		}
+	}
+	walk(entry)
+	s.start = len(s.nodes)
+	s.end = s.start + 1
+	s.succs = make([][]int, s.end+1)
+	for i, n := range s.nodes {
+		for _, succ := range sortByID(graph.NodesOf(g.From(n.ID()))) {
+			s.addEdge(i, index[succ.ID()], g.Edge(n.ID(), succ.ID()))
+		}
+		if len(s.succs[i]) == 0 {
+			s.addEdge(i, s.end, nil)
+		}
+	}
+	// Connect endless loops to the end node, by adding a virtual edge from the
+	// last node in depth first order which cannot reach the end node.
+	for {
+		reach := s.reachEnd()
+		last := -1
+		for i := range s.nodes {
+			if !reach[i] {
+				last = i
+			}
+		}
+		if last == -1 {
+			break
+		}
+		s.addEdge(last, s.end, nil)
+	}
+	s.addEdge(s.end, s.start, nil)
+	s.addEdge(s.start, 0, nil)
+	// Record the depth first order of nodes and edges, starting at the virtual
+	// start node.
+	s.pre = make([]int, len(s.succs))
+	for i := range s.pre {
+		s.pre[i] = -1
+	}
+	nnodes, nedges := 0, 0
+	var visit func(i int)
+	visit = func(i int) {
+		s.pre[i] = nnodes
+		nnodes++
+		for _, j := range s.succs[i] {
+			e := s.edges[j]
+			e.order = nedges
+			nedges++
+			if s.pre[e.to] == -1 {
+				visit(e.to)
+			}
+		}
+	}
+	visit(s.start)
+	return s
+}
+
+// addEdge adds an edge between the nodes of the given indices to the augmented
+// graph.
+func (s *sese) addEdge(from, to int, e graph.Edge) {
+	s.succs[from] = append(s.succs[from], len(s.edges))
+	s.edges = append(s.edges, &seseEdge{from: from, to: to, e: e})
+}
+
+// reachEnd returns the nodes from which the virtual end node is reachable,
+// indexed by node index.
+func (s *sese) reachEnd() []bool {
+	preds := make([][]int, len(s.succs))
+	for _, e := range s.edges {
+		preds[e.to] = append(preds[e.to], e.from)
+	}
+	reach := make([]bool, len(s.succs))
+	var walk func(i int)
+	walk = func(i int) {
+		reach[i] = true
+		for _, pred := range preds[i] {
+			if !reach[pred] {
+				walk(pred)
+			}
+		}
+	}
+	walk(s.end)
+	return reach
+}
+
+// --- [ Cycle equivalence ] ---------------------------------------------------
+
+// bracket is a backedge of the undirected depth first spanning tree, or a
+// capping backedge.
+type bracket struct {
+	// Edge index; or -1 if capping backedge.
+	edge int
+	// Cycle equivalence class of the backedge.
+	class int
+	// Size of the bracket list when the bracket was most recently the topmost
+	// bracket of a tree edge.
+	recentSize int
+	// Cycle equivalence class of the tree edge for which the bracket was most
+	// recently the topmost bracket.
+	recentClass int
+	// Adjacent brackets of the bracket list.
+	prev, next *bracket
+}
+
+// bracketList is a list of brackets, with the most recently pushed bracket on
+// top.
+type bracketList struct {
+	top, bottom *bracket
+	size        int
+}
+
+// push pushes b on top of l.
+func (l *bracketList) push(b *bracket) {
+	b.prev, b.next = nil, l.top
+	if l.top != nil {
+		l.top.prev = b
+	} else {
+		l.bottom = b
+	}
+	l.top = b
+	l.size++
+}
+
+// delete removes b from l.
+func (l *bracketList) delete(b *bracket) {
+	if b.prev != nil {
+		b.prev.next = b.next
+	} else {
+		l.top = b.next
+	}
+	if b.next != nil {
+		b.next.prev = b.prev
+	} else {
+		l.bottom = b.prev
+	}
+	b.prev, b.next = nil, nil
+	l.size--
+}
+
+// concat places the brackets of m on top of l, leaving m empty.
+func (l *bracketList) concat(m *bracketList) {
+	if m.size == 0 {
+		return
+	}
+	if l.size == 0 {
+		*l = *m
+	} else {
+		m.bottom.next = l.top
+		l.top.prev = m.bottom
+		l.top = m.top
+		l.size += m.size
+	}
+	*m = bracketList{}
+}
+
+// cycleEquiv assigns cycle equivalence classes to the edges of the augmented
+// graph, by treating it as undirected.
+func (s *sese) cycleEquiv() {
+	n := len(s.succs)
+	// Undirected adjacency lists; edge indices incident to each node.
+	adj := make([][]int, n)
+	for i, e := range s.edges {
+		if e.from == e.to {
+			continue
+		}
+		adj[e.from] = append(adj[e.from], i)
+		adj[e.to] = append(adj[e.to], i)
+	}
+	nclasses := 0
+	newClass := func() int {
+		nclasses++
+		return nclasses
+	}
+	// Self-loops are only cycle equivalent to themselves.
+	for _, e := range s.edges {
+		if e.from == e.to {
+			e.class = newClass()
+		}
+	}
+	// Undirected depth first search, recording tree edges and backedges.
+	dfsnum := make([]int, n)
+	for i := range dfsnum {
+		dfsnum[i] = -1
+	}
+	var order []int
+	parentEdge := make([]int, n)
+	children := make([][]int, n)
+	// Backedges from each node to its ancestors, and from descendants of each
+	// node to the node.
+	up := make([][]int, n)
+	down := make([][]int, n)
+	var walk func(i int)
+	walk = func(i int) {
+		dfsnum[i] = len(order)
+		order = append(order, i)
+		for _, j := range adj[i] {
+			if j == parentEdge[i] {
+				continue
+			}
+			e := s.edges[j]
+			m := e.to
+			if m == i {
+				m = e.from
+			}
+			switch {
+			case dfsnum[m] == -1:
+				parentEdge[m] = j
+				children[i] = append(children[i], m)
+				walk(m)
+			case dfsnum[m] < dfsnum[i]:
+				up[i] = append(up[i], j)
+				down[m] = append(down[m], j)
+			}
+		}
+	}
+	root := s.end
+	parentEdge[root] = -1
+	walk(root)
+	// Compute bracket lists in reverse depth first order.
+	const inf = int(^uint(0) >> 1)
+	hi := make([]int, n)
+	blists := make([]*bracketList, n)
+	brackets := make(map[int]*bracket)
+	capping := make([][]*bracket, n)
+	for k := len(order) - 1; k >= 0; k-- {
+		i := order[k]
+		hi0 := inf
+		for _, j := range up[i] {
+			e := s.edges[j]
+			t := e.to
+			if t == i {
+				t = e.from
+			}
+			if dfsnum[t] < hi0 {
+				hi0 = dfsnum[t]
+			}
+		}
+		hi1, hichild := inf, -1
+		for _, c := range children[i] {
+			if hi[c] < hi1 {
+				hi1, hichild = hi[c], c
+			}
+		}
+		hi[i] = hi0
+		if hi1 < hi[i] {
+			hi[i] = hi1
+		}
+		hi2 := inf
+		for _, c := range children[i] {
+			if c != hichild && hi[c] < hi2 {
+				hi2 = hi[c]
+			}
+		}
+		blist := &bracketList{}
+		for _, c := range children[i] {
+			blist.concat(blists[c])
+		}
+		for _, b := range capping[i] {
+			blist.delete(b)
+		}
+		for _, j := range down[i] {
+			b := brackets[j]
+			blist.delete(b)
+			if b.class == 0 {
+				b.class = newClass()
+			}
+			s.edges[j].class = b.class
+		}
+		for _, j := range up[i] {
+			b := &bracket{edge: j}
+			brackets[j] = b
+			blist.push(b)
+		}
+		if hi2 < hi0 && hi2 < dfsnum[i] {
+			// Create capping backedge, unless the brackets of the other children
+			// all end at i.
+			b := &bracket{edge: -1}
+			t := order[hi2]
+			capping[t] = append(capping[t], b)
+			blist.push(b)
+		}
+		blists[i] = blist
+		// Determine class of the tree edge from the parent of i to i.
+		if i != root {
+			b := blist.top
+			if b.recentSize != blist.size {
+				b.recentSize = blist.size
+				b.recentClass = newClass()
+			}
+			s.edges[parentEdge[i]].class = b.recentClass
+			if b.recentSize == 1 {
+				b.class = b.recentClass
+			}
+		}
+	}
+}
+
+// --- [ Program structure tree ] ----------------------------------------------
+
+// tree returns the program structure tree of the augmented graph.
+func (s *sese) tree() *Region {
+	root := s.region(nil, nil, 0, func(e *seseEdge) bool { return false })
+	// Canonical SESE regions are delimited by consecutive edges of each cycle
+	// equivalence class, in dominance order.
+	classes := make(map[int][]*seseEdge)
+	for _, e := range s.edges {
+		classes[e.class] = append(classes[e.class], e)
+	}
+	var regions []*Region
+	for _, es := range classes {
+		sort.Slice(es, func(i, j int) bool {
+			return es[i].order < es[j].order
+		})
+		for k := 0; k+1 < len(es); k++ {
+			entry, exit := es[k], es[k+1]
+			r := s.region(entry.e, exit.e, entry.to, func(e *seseEdge) bool { return e == exit })
+			// Skip regions consisting only of virtual nodes, and the region of the
+			// entire graph.
+			if len(r.Nodes) == 0 || len(r.Nodes) == len(root.Nodes) {
+				continue
+			}
+			regions = append(regions, r)
+		}
+	}
+	// Nest regions by placing larger regions first.
+	sort.Slice(regions, func(i, j int) bool {
+		if len(regions[i].Nodes) != len(regions[j].Nodes) {
+			return len(regions[i].Nodes) > len(regions[j].Nodes)
+		}
+		return regions[i].order < regions[j].order
+	})
+	for _, r := range regions {
+		parent := root
+	loop:
+		for {
+			for _, child := range parent.Children {
+				if child.Contains(r.Nodes[0]) {
+					parent = child
+					continue loop
+				}
+			}
+			break
+		}
+		r.Parent = parent
+		parent.Children = append(parent.Children, r)
+	}
+	var sortChildren func(r *Region)
+	sortChildren = func(r *Region) {
+		sort.Slice(r.Children, func(i, j int) bool {
+			return r.Children[i].order < r.Children[j].order
+		})
+		for _, child := range r.Children {
+			sortChildren(child)
+		}
+	}
+	sortChildren(root)
+	return root
+}
+
+// region returns the region of nodes reachable from the node of the given index
+// without traversing edges for which stop returns true.
+func (s *sese) region(entry, exit graph.Edge, head int, stop func(e *seseEdge) bool) *Region {
+	r := &Region{
+		Entry: entry,
+		Exit:  exit,
+		nodes: make(map[int64]graph.Node),
+		order: s.pre[head],
+	}
+	visited := make([]bool, len(s.succs))
+	var is []int
+	var walk func(i int)
+	walk = func(i int) {
+		visited[i] = true
+		if i < len(s.nodes) {
+			is = append(is, i)
+		}
+		for _, j := range s.succs[i] {
+			e := s.edges[j]
+			// Do not wrap around through the virtual start node.
+			if stop(e) || e.from == s.end {
+				continue
+			}
+			if !visited[e.to] {
+				walk(e.to)
+			}
+		}
+	}
+	walk(head)
+	sort.Slice(is, func(i, j int) bool {
+		return s.pre[is[i]] < s.pre[is[j]]
+	})
+	for _, i := range is {
+		n := s.nodes[i]
+		r.Nodes = append(r.Nodes, n)
+		r.nodes[n.ID()] = n
+	}
+	return r
+}
+
+// sortByID sorts the given list of nodes by node ID.
+func sortByID(nodes []graph.Node) []graph.Node {
+	sort.Slice(nodes, func(i, j int) bool {
+		return nodes[i].ID() < nodes[j].ID()
+	})
+	return nodes
+}
@@ -0,0 +1,410 @@
+// ref: Briggs, Preston, et al. "Practical improvements to the construction and
+// destruction of static single assignment form." Software: Practice and
+// Experience 28.8 (1998): 859-881.
+//
+// ref: Boissinot, Benoit, et al. "Revisiting out-of-SSA translation for
+// correctness, code quality and efficiency." 2009 International Symposium on
+// Code Generation and Optimization. IEEE, 2009.
+
+package flow
+
+import (
+	"fmt"
+	"math/big"
+
+	"github.com/graphism/exp/cfg"
+	"github.com/llir/llvm/ir"
+	"github.com/llir/llvm/ir/types"
+	"github.com/llir/llvm/ir/value"
+	"gonum.org/v1/gonum/graph"
+)
+
+// OutOfSSA is a function translated out of SSA form; phi instructions are
+// replaced by copies between variables on the incoming edges.
+type OutOfSSA struct {
+	// Variables of the function, in order of definition; followed by temporary
+	// variables.
+	Vars []*Var
+	// Variable of each local value.
+	vars map[value.Named]*Var
+	// Copies at the entry of each node; mapping from node ID to copies.
+	entry map[int64][]*Copy
+	// Copies at the exit of each node; mapping from node ID to copies.
+	exit map[int64][]*Copy
+}
+
+// Var is a variable of a function translated out of SSA form; local values
+// with non-interfering live ranges related by phi instructions are coalesced
+// into the same variable.
+type Var struct {
+	// Variable name.
+	Name string
+	// Local values assigned to the variable, in order of definition; or nil if
+	// temporary variable used to break copy cycles.
+	Values []value.Named
+	// Type of the variable.
+	typ types.Type
+}
+
+// Copy is a copy of a value into a variable.
+type Copy struct {
+	// Destination variable.
+	Dst *Var
+	// Source value; a variable if local value, and the original value
+	// otherwise (e.g. constant or global).
+	Src value.Value
+}
+
+// DestroySSA translates the function f out of SSA form, based on its control
+// flow graph g; as created by cfg.NewGraphFromFunc.
+//
+// Each phi instruction becomes a set of copies to its variable, one on each
+// incoming edge. The copies of an edge are placed at the exit of its source
+// node if it has a single successor, and otherwise at the entry of its
+// destination node if it has a single predecessor. Critical edges are split, by
+// adding a new node to g for the copies. The copies of each edge are
+// sequentialized, breaking cycles using temporary variables. The function f is
+// left unmodified.
+func DestroySSA(g *cfg.Graph, f *ir.Func) *OutOfSSA {
+	lv := LiveVars(g, f)
+	l := lv.l
+	// Compute interference of local values, for the program with copies in
+	// place of phi instructions.
+	ig := newInterference()
+	// starts records the local values live at the entry of each basic block,
+	// after the phi instructions.
+	starts := make(map[*ir.Block]*big.Int)
+	for _, block := range f.Blocks {
+		n := l.node(block)
+		live := new(big.Int).Or(lv.s.Out(n).(*big.Int), l.phiUses[n.ID()])
+		ig.step(l, live, block.Term)
+		for i := len(block.Insts) - 1; i >= 0; i-- {
+			inst := block.Insts[i]
+			if _, ok := inst.(*ir.InstPhi); ok {
+				// Phi instructions are replaced by copies on the incoming edges.
+				continue
+			}
+			ig.step(l, live, inst)
+		}
+		starts[block] = live
+	}
+	// Function parameters are defined simultaneously at the entry of the
+	// function.
+	if len(f.Blocks) > 0 {
+		live := starts[f.Blocks[0]]
+		for _, param := range f.Params {
+			ig.def(l.index[param], new(big.Int).Set(live))
+		}
+	}
+	// Parallel copies of each incoming edge of phi instructions.
+	edges := phiEdges(l, f)
+	for _, e := range edges {
+		for i, c := range e.copies {
+			ig.def(c.dst, new(big.Int).Set(starts[e.block]), c.src)
+			// Destinations of a parallel copy are defined simultaneously.
+			for _, d := range e.copies[:i] {
+				ig.add(c.dst, d.dst)
+			}
+		}
+	}
+	// Coalesce local values related by phi instructions, unless interfering.
+	for _, e := range edges {
+		for _, c := range e.copies {
+			if c.src != -1 {
+				ig.coalesce(c.dst, c.src)
+			}
+		}
+	}
+	// Create variables.
+	o := &OutOfSSA{
+		vars:  make(map[value.Named]*Var),
+		entry: make(map[int64][]*Copy),
+		exit:  make(map[int64][]*Copy),
+	}
+	classes := make(map[int]*Var)
+	for i, v := range l.values {
+		root := ig.find(i)
+		x, ok := classes[root]
+		if !ok {
+			x = &Var{Name: v.Name(), typ: v.Type()}
+			classes[root] = x
+			o.Vars = append(o.Vars, x)
+		}
+		x.Values = append(x.Values, v)
+		o.vars[v] = x
+	}
+	// Place copies.
+	for _, e := range edges {
+		var copies []*Copy
+		for _, c := range e.copies {
+			dst := o.vars[l.values[c.dst]]
+			src := c.val
+			if c.src != -1 {
+				src = o.vars[l.values[c.src]]
+			}
+			if src == dst {
+				// Coalesced.
+				continue
+			}
+			copies = append(copies, &Copy{Dst: dst, Src: src})
+		}
+		if len(copies) == 0 {
+			continue
+		}
+		copies = o.sequentialize(copies)
+		from, to := l.nodeWithName(e.pred), l.node(e.block)
+		switch {
+		case g.From(from.ID()).Len() == 1:
+			o.exit[from.ID()] = append(o.exit[from.ID()], copies...)
+		case g.To(to.ID()).Len() == 1:
+			o.entry[to.ID()] = append(o.entry[to.ID()], copies...)
+		default:
+			n := cfg.SplitEdge(g, from, to)
+			o.exit[n.ID()] = copies
+		}
+	}
+	return o
+}
+
+// Var returns the variable of the given local value.
+func (o *OutOfSSA) Var(v value.Named) *Var {
+	return o.vars[v]
+}
+
+// EntryCopies returns the copies at the entry of the given node, executed in
+// order after control is transferred from its unique predecessor.
+func (o *OutOfSSA) EntryCopies(n graph.Node) []*Copy {
+	return o.entry[n.ID()]
+}
+
+// ExitCopies returns the copies at the exit of the given node, executed in order
+// before control is transferred to its unique successor.
+func (o *OutOfSSA) ExitCopies(n graph.Node) []*Copy {
+	return o.exit[n.ID()]
+}
+
+// sequentialize returns the given parallel copies as a sequence of copies,
+// breaking copy cycles using temporary variables.
+func (o *OutOfSSA) sequentialize(copies []*Copy) []*Copy {
+	var seq []*Copy
+	for len(copies) > 0 {
+		// isSrc reports whether the given variable is read by a pending copy.
+		isSrc := func(x *Var) bool {
+			for _, c := range copies {
+				if c.Src == x {
+					return true
+				}
+			}
+			return false
+		}
+		ready := -1
+		for i, c := range copies {
+			if !isSrc(c.Dst) {
+				ready = i
+				break
+			}
+		}
+		if ready == -1 {
+			// Break cycle by saving the value of a destination variable in a
+			// temporary variable, and reading from the temporary.
+			dst := copies[0].Dst
+			tmp := o.newTemp(dst)
+			seq = append(seq, &Copy{Dst: tmp, Src: dst})
+			for _, c := range copies {
+				if c.Src == dst {
+					c.Src = tmp
+				}
+			}
+			continue
+		}
+		seq = append(seq, copies[ready])
+		copies = append(copies[:ready], copies[ready+1:]...)
+	}
+	return seq
+}
+
+// newTemp returns a new temporary variable of the same type as x.
+func (o *OutOfSSA) newTemp(x *Var) *Var {
+	names := make(map[string]bool)
+	for _, v := range o.Vars {
+		names[v.Name] = true
+	}
+	for i := 1; ; i++ {
+		name := fmt.Sprintf("tmp%d", i)
+		if !names[name] {
+			tmp := &Var{Name: name, typ: x.typ}
+			o.Vars = append(o.Vars, tmp)
+			return tmp
+		}
+	}
+}
+
+// --- [ value.Value ] ---------------------------------------------------------
+
+// String returns the LLVM syntax representation of the variable as a
+// type-value pair.
+func (x *Var) String() string {
+	return fmt.Sprintf("%s %s", x.Type(), x.Ident())
+}
+
+// Type returns the type of the variable.
+func (x *Var) Type() types.Type {
+	return x.typ
+}
+
+// Ident returns the identifier associated with the variable.
+func (x *Var) Ident() string {
+	return "%" + x.Name
+}
+
+// --- [ Phi edges ] -----------------------------------------------------------
+
+// phiEdge is an incoming edge of the phi instructions of a basic block.
+type phiEdge struct {
+	// Name of incoming basic block.
+	pred string
+	// Basic block of the phi instructions.
+	block *ir.Block
+	// Parallel copies of the edge.
+	copies []*phiCopy
+}
+
+// phiCopy is a copy of a phi operand to the local value of the phi
+// instruction, in terms of value indices.
+type phiCopy struct {
+	// Local value defined by the phi instruction.
+	dst int
+	// Phi operand; or -1 if not a local value.
+	src int
+	// Phi operand.
+	val value.Value
+}
+
+// phiEdges returns the incoming edges of the phi instructions of the function
+// f, in order of occurrence.
+func phiEdges(l *locals, f *ir.Func) []*phiEdge {
+	var edges []*phiEdge
+	for _, block := range f.Blocks {
+		m := make(map[string]*phiEdge)
+		for _, inst := range block.Insts {
+			phi, ok := inst.(*ir.InstPhi)
+			if !ok {
+				continue
+			}
+			for _, inc := range phi.Incs {
+				pred := inc.Pred.(value.Named).Name()
+				e, ok := m[pred]
+				if !ok {
+					e = &phiEdge{pred: pred, block: block}
+					m[pred] = e
+					edges = append(edges, e)
+				}
+				c := &phiCopy{dst: l.index[phi], src: -1, val: inc.X}
+				if i, ok := l.indexOf(inc.X); ok {
+					c.src = i
+				}
+				e.copies = append(e.copies, c)
+			}
+		}
+	}
+	return edges
+}
+
+// --- [ Interference ] --------------------------------------------------------
+
+// interference is an interference graph of local values, with coalescing of
+// non-interfering values into classes.
+type interference struct {
+	// Interfering classes of each class; mapping from class representative to
+	// interfering value indices.
+	edges map[int]map[int]bool
+	// Parent of each value index in the union-find forest of classes.
+	parent map[int]int
+}
+
+// newInterference returns a new interference graph.
+func newInterference() *interference {
+	return &interference{
+		edges:  make(map[int]map[int]bool),
+		parent: make(map[int]int),
+	}
+}
+
+// step updates the interference graph and set of live values by walking
+// backwards over the given instruction or terminator.
+func (ig *interference) step(l *locals, live *big.Int, inst interface{}) {
+	if def, ok := defOf(inst); ok {
+		ig.def(l.index[def], live)
+	}
+	if user, ok := inst.(value.User); ok {
+		for _, operand := range user.Operands() {
+			if i, ok := l.indexOf(*operand); ok {
+				live.SetBit(live, i, 1)
+			}
+		}
+	}
+}
+
+// def records the definition of the value index d, which interferes with the
+// values live after the definition; except for the source values of copies.
+// The value d is removed from the set of live values.
+func (ig *interference) def(d int, live *big.Int, srcs ...int) {
+loop:
+	for i := 0; i < live.BitLen(); i++ {
+		if i == d || live.Bit(i) == 0 {
+			continue
+		}
+		for _, src := range srcs {
+			if i == src {
+				continue loop
+			}
+		}
+		ig.add(d, i)
+	}
+	live.SetBit(live, d, 0)
+}
+
+// add adds an interference edge between the value indices x and y.
+func (ig *interference) add(x, y int) {
+	x, y = ig.find(x), ig.find(y)
+	if x == y {
+		return
+	}
+	if ig.edges[x] == nil {
+		ig.edges[x] = make(map[int]bool)
+	}
+	if ig.edges[y] == nil {
+		ig.edges[y] = make(map[int]bool)
+	}
+	ig.edges[x][y] = true
+	ig.edges[y][x] = true
+}
+
+// find returns the representative of the class of the value index x.
+func (ig *interference) find(x int) int {
+	for {
+		p, ok := ig.parent[x]
+		if !ok {
+			return x
+		}
+		x = p
+	}
+}
+
+// coalesce merges the classes of the value indices x and y, unless they
+// interfere. The representative of the merged class is the least value index.
+func (ig *interference) coalesce(x, y int) {
+	x, y = ig.find(x), ig.find(y)
+	if x == y || ig.edges[x][y] {
+		return
+	}
+	if y < x {
+		x, y = y, x
+	}
+	ig.parent[y] = x
+	for z := range ig.edges[y] {
+		delete(ig.edges[z], y)
+		ig.add(x, z)
+	}
+	delete(ig.edges, y)
+}
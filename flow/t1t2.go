@@ -0,0 +1,173 @@
+// ref: Hecht, Matthew S., and Jeffrey D. Ullman. "Flow graph reducibility."
+// SIAM Journal on Computing 1.2 (1972): 188-202.
+
+package flow
+
+import (
+	"fmt"
+
+	"gonum.org/v1/gonum/graph"
+)
+
+// Transform specifies a T1/T2 transformation.
+type Transform uint8
+
+// T1/T2 transformations.
+const (
+	// T1 removes a self-loop.
+	T1 Transform = iota + 1
+	// T2 merges a node into its unique predecessor.
+	T2
+)
+
+// String returns the string representation of the transformation.
+func (t Transform) String() string {
+	switch t {
+	case T1:
+		return "T1"
+	case T2:
+		return "T2"
+	default:
+		return fmt.Sprintf("Transform(%d)", uint8(t))
+	}
+}
+
+// Step is a step of the T1/T2 reduction of a graph.
+type Step struct {
+	// Transformation applied.
+	Transform Transform
+	// Node of the removed self-loop (T1), or node merged into its unique
+	// predecessor (T2).
+	Node graph.Node
+	// Unique predecessor into which Node is merged (T2); or nil (T1).
+	Pred graph.Node
+}
+
+// Reduce applies the T1 and T2 transformations of Hecht and Ullman to the
+// subgraph of g reachable from entry until neither applies, and returns the
+// reduction sequence and the nodes of the limit graph. Each node of the limit
+// graph is the node of g into which the other nodes of its region were merged.
+// The graph g is left unmodified.
+//
+// Self-loops are removed as soon as they appear, and nodes are otherwise merged
+// in depth first order.
+func Reduce(g graph.Directed, entry graph.Node) (steps []Step, limit []graph.Node) {
+	r := newReduction(g, entry)
+	for {
+		if n, ok := r.selfLoop(); ok {
+			delete(r.succs[n], n)
+			delete(r.preds[n], n)
+			steps = append(steps, Step{Transform: T1, Node: r.nodes[n]})
+			continue
+		}
+		if n, pred, ok := r.uniquePred(); ok {
+			r.merge(n, pred)
+			steps = append(steps, Step{Transform: T2, Node: r.nodes[n], Pred: r.nodes[pred]})
+			continue
+		}
+		break
+	}
+	for _, id := range r.order {
+		if r.alive(id) {
+			limit = append(limit, r.nodes[id])
+		}
+	}
+	return steps, limit
+}
+
+// IsReducible reports whether the subgraph of g reachable from entry is
+// reducible; i.e. whether the T1 and T2 transformations reduce it to a single
+// node.
+func IsReducible(g graph.Directed, entry graph.Node) bool {
+	_, limit := Reduce(g, entry)
+	return len(limit) == 1
+}
+
+// reduction tracks the state of T1/T2 reduction.
+type reduction struct {
+	// Entry node ID.
+	entry int64
+	// Nodes of the graph, mapping from node ID to node.
+	nodes map[int64]graph.Node
+	// Node IDs in depth first order.
+	order []int64
+	// Successors and predecessors of each remaining node.
+	succs, preds map[int64]map[int64]bool
+}
+
+// newReduction returns the reduction state of the subgraph of g reachable from
+// entry.
+func newReduction(g graph.Directed, entry graph.Node) *reduction {
+	r := &reduction{
+		entry: entry.ID(),
+		nodes: make(map[int64]graph.Node),
+		succs: make(map[int64]map[int64]bool),
+		preds: make(map[int64]map[int64]bool),
+	}
+	var walk func(n graph.Node)
+	walk = func(n graph.Node) {
+		id := n.ID()
+		r.nodes[id] = n
+		r.order = append(r.order, id)
+		r.succs[id] = make(map[int64]bool)
+		for _, succ := range sortByID(graph.NodesOf(g.From(id))) {
+			if _, ok := r.nodes[succ.ID()]; !ok {
+				walk(succ)
+			}
+		}
+	}
+	walk(entry)
+	for _, id := range r.order {
+		r.preds[id] = make(map[int64]bool)
+	}
+	for _, id := range r.order {
+		for _, succ := range graph.NodesOf(g.From(id)) {
+			r.succs[id][succ.ID()] = true
+			r.preds[succ.ID()][id] = true
+		}
+	}
+	return r
+}
+
+// alive reports whether the node of the given ID remains in the graph.
+func (r *reduction) alive(id int64) bool {
+	_, ok := r.succs[id]
+	return ok
+}
+
+// selfLoop returns the first node with a self-loop, and a boolean variable
+// indicating success.
+func (r *reduction) selfLoop() (int64, bool) {
+	for _, id := range r.order {
+		if r.alive(id) && r.succs[id][id] {
+			return id, true
+		}
+	}
+	return 0, false
+}
+
+// uniquePred returns the first non-entry node with a unique predecessor, the
+// predecessor, and a boolean variable indicating success.
+func (r *reduction) uniquePred() (int64, int64, bool) {
+	for _, id := range r.order {
+		if id == r.entry || !r.alive(id) || len(r.preds[id]) != 1 {
+			continue
+		}
+		for pred := range r.preds[id] {
+			return id, pred, true
+		}
+	}
+	return 0, 0, false
+}
+
+// merge merges the node n into its unique predecessor pred.
+func (r *reduction) merge(n, pred int64) {
+	delete(r.succs[pred], n)
+	for succ := range r.succs[n] {
+		delete(r.preds[succ], n)
+		r.succs[pred][succ] = true
+		r.preds[succ][pred] = true
+	}
+	delete(r.succs, n)
+	delete(r.preds, n)
+}
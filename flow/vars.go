@@ -0,0 +1,386 @@
+// ref: Aho, Alfred V., et al. "Compilers: principles, techniques, and tools."
+// 2nd ed. Addison-Wesley, 2006. Section 9.2.
+
+package flow
+
+import (
+	"fmt"
+	"math/big"
+	"strings"
+
+	"github.com/graphism/exp/cfg"
+	"github.com/llir/llvm/ir"
+	"github.com/llir/llvm/ir/types"
+	"github.com/llir/llvm/ir/value"
+)
+
+// === [ Live variables ] ======================================================
+
+// Liveness records the local values live at the entry and exit of each basic
+// block of a function.
+//
+// Operands of phi instructions are attributed to the incoming edges; they are
+// live at the exit of the incoming block, but not at the entry of the block of
+// the phi instruction. Values defined by phi instructions are defined at the
+// entry of their block, and are thus not live at its entry.
+type Liveness struct {
+	// Local values of the function.
+	l *locals
+	// Solution of the dataflow problem.
+	s *Solution
+}
+
+// LiveVars computes the live variables of the function f, based on its control
+// flow graph g; as created by cfg.NewGraphFromFunc.
+func LiveVars(g *cfg.Graph, f *ir.Func) *Liveness {
+	l := newLocals(g, f)
+	p := &Problem{
+		Dir:      Backward,
+		Lattice:  bitsetLattice{},
+		Boundary: new(big.Int),
+		Transfer: func(n *cfg.Node, fact Fact) Fact {
+			// in = use ∪ (out ∪ phiUse - def)
+			x := new(big.Int).Or(fact.(*big.Int), l.phiUses[n.ID()])
+			x.AndNot(x, l.defs[n.ID()])
+			return x.Or(x, l.uses[n.ID()])
+		},
+	}
+	return &Liveness{l: l, s: Solve(g, p)}
+}
+
+// In returns the local values live at the entry of the given basic block, in
+// order of definition.
+func (lv *Liveness) In(block *ir.Block) []value.Named {
+	return lv.l.valuesOf(lv.s.In(lv.l.node(block)).(*big.Int))
+}
+
+// Out returns the local values live at the exit of the given basic block, in
+// order of definition; including the operands of phi instructions of
+// successors attributed to the outgoing edges of the block.
+func (lv *Liveness) Out(block *ir.Block) []value.Named {
+	n := lv.l.node(block)
+	out := new(big.Int).Or(lv.s.Out(n).(*big.Int), lv.l.phiUses[n.ID()])
+	return lv.l.valuesOf(out)
+}
+
+// === [ Reaching definitions ] ================================================
+
+// ReachingDefs records the definitions of local values reaching the entry and
+// exit of each basic block of a function. Function parameters are defined at
+// the entry of the function.
+type ReachingDefs struct {
+	// Local values of the function.
+	l *locals
+	// Solution of the dataflow problem.
+	s *Solution
+}
+
+// ReachDefs computes the reaching definitions of the function f, based on its
+// control flow graph g; as created by cfg.NewGraphFromFunc.
+func ReachDefs(g *cfg.Graph, f *ir.Func) *ReachingDefs {
+	l := newLocals(g, f)
+	params := new(big.Int)
+	for _, param := range f.Params {
+		params.SetBit(params, l.index[param], 1)
+	}
+	p := &Problem{
+		Dir:      Forward,
+		Lattice:  bitsetLattice{},
+		Boundary: params,
+		Transfer: func(n *cfg.Node, fact Fact) Fact {
+			// Local values are assigned once (SSA form), thus no definitions are
+			// killed.
+			//
+			// out = def ∪ in
+			return new(big.Int).Or(fact.(*big.Int), l.defs[n.ID()])
+		},
+	}
+	return &ReachingDefs{l: l, s: Solve(g, p)}
+}
+
+// In returns the definitions reaching the entry of the given basic block, in
+// order of definition.
+func (rd *ReachingDefs) In(block *ir.Block) []value.Named {
+	return rd.l.valuesOf(rd.s.In(rd.l.node(block)).(*big.Int))
+}
+
+// Out returns the definitions reaching the exit of the given basic block, in
+// order of definition.
+func (rd *ReachingDefs) Out(block *ir.Block) []value.Named {
+	return rd.l.valuesOf(rd.s.Out(rd.l.node(block)).(*big.Int))
+}
+
+// === [ Def-use chains ] ======================================================
+
+// Use is a use of a local value.
+type Use struct {
+	// Instruction or terminator using the value.
+	User value.User
+	// Basic block containing the user.
+	Block *ir.Block
+	// Incoming block of the phi operand; the use is attributed to the edge from
+	// Pred to Block. Pred is nil if the user is not a phi instruction.
+	Pred *ir.Block
+}
+
+// DefUse records the def-use and use-def chains of the local values of a
+// function.
+type DefUse struct {
+	// Uses of each local value; mapping from definition to uses in order of
+	// occurrence.
+	uses map[value.Named][]*Use
+	// Local values used by each instruction and terminator; mapping from user
+	// to definitions in operand order.
+	defs map[value.User][]value.Named
+}
+
+// DefUseChains computes the def-use and use-def chains of the local values of
+// the function f.
+func DefUseChains(f *ir.Func) *DefUse {
+	du := &DefUse{
+		uses: make(map[value.Named][]*Use),
+		defs: make(map[value.User][]value.Named),
+	}
+	blocks := blocksByName(f)
+	for _, block := range f.Blocks {
+		for _, inst := range block.Insts {
+			user, ok := inst.(value.User)
+			if !ok {
+				continue
+			}
+			if phi, ok := inst.(*ir.InstPhi); ok {
+				for _, inc := range phi.Incs {
+					if def, ok := localOf(inc.X); ok {
+						pred := blocks[inc.Pred.(value.Named).Name()]
+						du.addUse(def, &Use{User: user, Block: block, Pred: pred})
+					}
+				}
+				continue
+			}
+			du.addUses(user, block)
+		}
+		if user, ok := block.Term.(value.User); ok {
+			du.addUses(user, block)
+		}
+	}
+	return du
+}
+
+// Uses returns the uses of the given local value, in order of occurrence.
+func (du *DefUse) Uses(def value.Named) []*Use {
+	return du.uses[def]
+}
+
+// Defs returns the local values used by the given instruction or terminator, in
+// operand order. In SSA form, the definition of each used local value is the
+// value itself.
+func (du *DefUse) Defs(user value.User) []value.Named {
+	return du.defs[user]
+}
+
+// addUses records the uses of the local operands of the given user, located in
+// the specified basic block.
+func (du *DefUse) addUses(user value.User, block *ir.Block) {
+	for _, operand := range user.Operands() {
+		if def, ok := localOf(*operand); ok {
+			du.addUse(def, &Use{User: user, Block: block})
+		}
+	}
+}
+
+// addUse records the given use of def.
+func (du *DefUse) addUse(def value.Named, use *Use) {
+	du.uses[def] = append(du.uses[def], use)
+	du.defs[use.User] = append(du.defs[use.User], def)
+}
+
+// === [ Local values ] ========================================================
+
+// locals tracks the local values of a function, and the local values defined
+// and used by each basic block of its control flow graph.
+type locals struct {
+	// Control flow graph of the function.
+	g *cfg.Graph
+	// Local values in order of definition; function parameters followed by
+	// instructions and terminators.
+	values []value.Named
+	// Index of each local value in values.
+	index map[value.Named]int
+	// Local values defined by each basic block; mapping from node ID to set of
+	// value indices.
+	defs map[int64]*big.Int
+	// Local values used by each basic block before being defined within the
+	// block (upward exposed uses), excluding the operands of phi instructions;
+	// mapping from node ID to set of value indices.
+	uses map[int64]*big.Int
+	// Operands of phi instructions attributed to the outgoing edges of each
+	// basic block; mapping from node ID to set of value indices.
+	phiUses map[int64]*big.Int
+}
+
+// newLocals returns the local values of the function f, based on its control
+// flow graph g.
+func newLocals(g *cfg.Graph, f *ir.Func) *locals {
+	l := &locals{
+		g:       g,
+		index:   make(map[value.Named]int),
+		defs:    make(map[int64]*big.Int),
+		uses:    make(map[int64]*big.Int),
+		phiUses: make(map[int64]*big.Int),
+	}
+	for _, param := range f.Params {
+		l.addValue(param)
+	}
+	for _, block := range f.Blocks {
+		for _, inst := range block.Insts {
+			if def, ok := defOf(inst); ok {
+				l.addValue(def)
+			}
+		}
+		if def, ok := defOf(block.Term); ok {
+			l.addValue(def)
+		}
+	}
+	nodes := g.Nodes()
+	for nodes.Next() {
+		id := nodes.Node().ID()
+		l.defs[id] = new(big.Int)
+		l.uses[id] = new(big.Int)
+		l.phiUses[id] = new(big.Int)
+	}
+	for _, block := range f.Blocks {
+		id := l.node(block).ID()
+		defs, uses := l.defs[id], l.uses[id]
+		use := func(v value.Value) {
+			if i, ok := l.indexOf(v); ok && defs.Bit(i) == 0 {
+				uses.SetBit(uses, i, 1)
+			}
+		}
+		for _, inst := range block.Insts {
+			if phi, ok := inst.(*ir.InstPhi); ok {
+				for _, inc := range phi.Incs {
+					if i, ok := l.indexOf(inc.X); ok {
+						pred := l.nodeWithName(inc.Pred.(value.Named).Name())
+						phiUses := l.phiUses[pred.ID()]
+						phiUses.SetBit(phiUses, i, 1)
+					}
+				}
+			} else if user, ok := inst.(value.User); ok {
+				for _, operand := range user.Operands() {
+					use(*operand)
+				}
+			}
+			if def, ok := defOf(inst); ok {
+				defs.SetBit(defs, l.index[def], 1)
+			}
+		}
+		if user, ok := block.Term.(value.User); ok {
+			for _, operand := range user.Operands() {
+				use(*operand)
+			}
+		}
+		if def, ok := defOf(block.Term); ok {
+			defs.SetBit(defs, l.index[def], 1)
+		}
+	}
+	return l
+}
+
+// addValue adds the given local value.
+func (l *locals) addValue(v value.Named) {
+	l.index[v] = len(l.values)
+	l.values = append(l.values, v)
+}
+
+// indexOf returns the index of the given value, and a boolean variable
+// indicating whether the value is a local value of the function.
+func (l *locals) indexOf(v value.Value) (int, bool) {
+	def, ok := localOf(v)
+	if !ok {
+		return 0, false
+	}
+	i, ok := l.index[def]
+	return i, ok
+}
+
+// valuesOf returns the local values of the given set of value indices, in order
+// of definition.
+func (l *locals) valuesOf(x *big.Int) []value.Named {
+	var vs []value.Named
+	for i, v := range l.values {
+		if x.Bit(i) != 0 {
+			vs = append(vs, v)
+		}
+	}
+	return vs
+}
+
+// node returns the node of the given basic block.
+func (l *locals) node(block *ir.Block) *cfg.Node {
+	return l.nodeWithName(block.Name())
+}
+
+// nodeWithName returns the node of the basic block with the given name.
+func (l *locals) nodeWithName(name string) *cfg.Node {
+	n, ok := l.g.NodeWithName(name)
+	if !ok {
+		panic(fmt.Errorf("unable to locate node of basic block %q", name))
+	}
+	return n
+}
+
+// defOf returns the local value defined by the given instruction or
+// terminator, and a boolean variable indicating success.
+func defOf(inst interface{}) (value.Named, bool) {
+	def, ok := inst.(value.Named)
+	if !ok {
+		return nil, false
+	}
+	if _, ok := def.Type().(*types.VoidType); ok {
+		return nil, false
+	}
+	return def, true
+}
+
+// localOf returns the given value as a local value, and a boolean variable
+// indicating success. Basic blocks are not considered local values.
+func localOf(v value.Value) (value.Named, bool) {
+	if _, ok := v.(*ir.Block); ok {
+		return nil, false
+	}
+	def, ok := v.(value.Named)
+	if !ok || !strings.HasPrefix(def.Ident(), "%") {
+		return nil, false
+	}
+	return def, true
+}
+
+// blocksByName returns the basic blocks of the function f; mapping from block
+// name to basic block.
+func blocksByName(f *ir.Func) map[string]*ir.Block {
+	blocks := make(map[string]*ir.Block)
+	for _, block := range f.Blocks {
+		blocks[block.Name()] = block
+	}
+	return blocks
+}
+
+// --- [ Lattice ] -------------------------------------------------------------
+
+// bitsetLattice is the lattice of sets of value indices, ordered by inclusion.
+type bitsetLattice struct{}
+
+// Bottom returns the empty set.
+func (bitsetLattice) Bottom() Fact {
+	return new(big.Int)
+}
+
+// Join returns the union of a and b.
+func (bitsetLattice) Join(a, b Fact) Fact {
+	return new(big.Int).Or(a.(*big.Int), b.(*big.Int))
+}
+
+// Equal reports whether a and b are equal.
+func (bitsetLattice) Equal(a, b Fact) bool {
+	return a.(*big.Int).Cmp(b.(*big.Int)) == 0
+}
@@ -0,0 +1,841 @@
+// Package logic provides boolean expressions over symbols.
+package logic
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Expr is a boolean expression.
+//
+// Expression nodes have one of the following underlying types.
+//
+//    logic.Const
+//    logic.Sym
+//    logic.Eq
+//    *logic.Not
+//    *logic.And
+//    *logic.Or
+type Expr interface {
+	fmt.Stringer
+	// isExpr ensures that only boolean expressions can be assigned to the
+	// logic.Expr interface.
+	isExpr()
+}
+
+// Const is a boolean constant.
+type Const bool
+
+// Boolean constants.
+const (
+	True  Const = true
+	False Const = false
+)
+
+// Sym is a boolean symbol.
+type Sym string
+
+// Eq is the comparison of a symbol with a value; e.g. of the tag of an n-way
+// conditional with a case value. Comparisons of the same symbol with distinct
+// values are mutually exclusive.
+type Eq struct {
+	// Compared symbol.
+	X Sym
+	// Compared value.
+	Value string
+}
+
+// Not is the negation of a boolean expression.
+type Not struct {
+	X Expr
+}
+
+// And is the conjunction of boolean expressions.
+type And struct {
+	Xs []Expr
+}
+
+// Or is the disjunction of boolean expressions.
+type Or struct {
+	Xs []Expr
+}
+
+// isExpr ensures that only boolean expressions can be assigned to the
+// logic.Expr interface.
+func (Const) isExpr() {}
+func (Sym) isExpr()   {}
+func (Eq) isExpr()    {}
+func (*Not) isExpr()  {}
+func (*And) isExpr()  {}
+func (*Or) isExpr()   {}
+
+// NewNot returns the negation of x, eliminating double negations and negated
+// constants.
+func NewNot(x Expr) Expr {
+	switch x := x.(type) {
+	case Const:
+		return !x
+	case *Not:
+		return x.X
+	}
+	return &Not{X: x}
+}
+
+// NewAnd returns the conjunction of xs, flattening nested conjunctions and
+// folding constants.
+func NewAnd(xs ...Expr) Expr {
+	var ys []Expr
+	for _, x := range xs {
+		switch x := x.(type) {
+		case Const:
+			if !x {
+				return False
+			}
+		case *And:
+			ys = append(ys, x.Xs...)
+		default:
+			ys = append(ys, x)
+		}
+	}
+	switch len(ys) {
+	case 0:
+		return True
+	case 1:
+		return ys[0]
+	}
+	return &And{Xs: ys}
+}
+
+// NewOr returns the disjunction of xs, flattening nested disjunctions and
+// folding constants.
+func NewOr(xs ...Expr) Expr {
+	var ys []Expr
+	for _, x := range xs {
+		switch x := x.(type) {
+		case Const:
+			if x {
+				return True
+			}
+		case *Or:
+			ys = append(ys, x.Xs...)
+		default:
+			ys = append(ys, x)
+		}
+	}
+	switch len(ys) {
+	case 0:
+		return False
+	case 1:
+		return ys[0]
+	}
+	return &Or{Xs: ys}
+}
+
+// --- [ fmt.Stringer ] --------------------------------------------------------
+
+// String returns the string representation of the boolean constant in Go
+// syntax.
+func (x Const) String() string {
+	if x {
+		return "true"
+	}
+	return "false"
+}
+
+// String returns the string representation of the boolean symbol in Go syntax.
+func (x Sym) String() string {
+	return string(x)
+}
+
+// String returns the string representation of the comparison in Go syntax.
+func (x Eq) String() string {
+	return fmt.Sprintf("%s == %s", x.X, x.Value)
+}
+
+// String returns the string representation of the negation in Go syntax.
+func (x *Not) String() string {
+	if y, ok := x.X.(Eq); ok {
+		return fmt.Sprintf("%s != %s", y.X, y.Value)
+	}
+	if y, ok := x.X.(Sym); ok && !strings.Contains(string(y), " ") {
+		return "!" + y.String()
+	}
+	if y, ok := x.X.(Const); ok {
+		return "!" + y.String()
+	}
+	return fmt.Sprintf("!(%v)", x.X)
+}
+
+// String returns the string representation of the conjunction in Go syntax.
+func (x *And) String() string {
+	var ss []string
+	for _, y := range x.Xs {
+		// Disjunctions bind weaker than conjunctions.
+		if _, ok := y.(*Or); ok {
+			ss = append(ss, fmt.Sprintf("(%v)", y))
+		} else {
+			ss = append(ss, y.String())
+		}
+	}
+	return strings.Join(ss, " && ")
+}
+
+// String returns the string representation of the disjunction in Go syntax.
+func (x *Or) String() string {
+	var ss []string
+	for _, y := range x.Xs {
+		ss = append(ss, y.String())
+	}
+	return strings.Join(ss, " || ")
+}
+
+// ### [ Normalization ] #######################################################
+
+// NNF returns the negation normal form of x; i.e. an equivalent expression in
+// which negations are only applied to symbols.
+func NNF(x Expr) Expr {
+	switch x := x.(type) {
+	case Const, Sym, Eq:
+		return x
+	case *Not:
+		switch y := x.X.(type) {
+		case Const:
+			return !y
+		case Sym, Eq:
+			return x
+		case *Not:
+			return NNF(y.X)
+		case *And:
+			// De Morgan; !(x && y) = !x || !y
+			var zs []Expr
+			for _, z := range y.Xs {
+				zs = append(zs, NNF(NewNot(z)))
+			}
+			return NewOr(zs...)
+		case *Or:
+			// De Morgan; !(x || y) = !x && !y
+			var zs []Expr
+			for _, z := range y.Xs {
+				zs = append(zs, NNF(NewNot(z)))
+			}
+			return NewAnd(zs...)
+		}
+	case *And:
+		var ys []Expr
+		for _, y := range x.Xs {
+			ys = append(ys, NNF(y))
+		}
+		return NewAnd(ys...)
+	case *Or:
+		var ys []Expr
+		for _, y := range x.Xs {
+			ys = append(ys, NNF(y))
+		}
+		return NewOr(ys...)
+	}
+	panic(fmt.Errorf("support for boolean expression %T not yet implemented", x))
+}
+
+// DNF returns the disjunctive normal form of x; i.e. an equivalent disjunction
+// of conjunctions of literals (possibly negated symbols and comparisons).
+//
+// The literals of each conjunction are sorted by symbol, and literals implied by
+// other literals of the conjunction are dropped (e.g. x != 2 is implied by
+// x == 1). The following simplifications are applied to the conjunctions, until
+// no more apply.
+//
+//    complementation       x && !x = false
+//    absorption            x || (x && y) = x
+//    negated absorption    (x && l) || (y && !l) = (x && l) || y, where y implies x
+func DNF(x Expr) Expr {
+	return fromTerms(terms(x))
+}
+
+// terms returns the simplified conjunctions of the disjunctive normal form of x,
+// as lists of literals.
+func terms(x Expr) [][]Expr {
+	var ts [][]Expr
+	for _, t := range dnf(NNF(x)) {
+		if t, ok := newTerm(t); ok {
+			ts = append(ts, t)
+		}
+	}
+	for {
+		var ok bool
+		if ts, ok = simplifyTerms(ts); !ok {
+			return ts
+		}
+	}
+}
+
+// newTerm returns the conjunction of the given literals, sorted and without
+// literals implied by other literals, and a boolean variable indicating
+// success; i.e. that the conjunction is not a contradiction.
+func newTerm(lits []Expr) ([]Expr, bool) {
+	var t []Expr
+	for i, lit := range lits {
+		redundant := containsExpr(t, lit)
+		for j, other := range lits {
+			if i == j {
+				continue
+			}
+			if implies(other, NNF(NewNot(lit))) {
+				return nil, false
+			}
+			if implies(other, lit) && other.String() != lit.String() {
+				redundant = true
+			}
+		}
+		if !redundant {
+			t = append(t, lit)
+		}
+	}
+	sort.SliceStable(t, func(i, j int) bool {
+		return litLess(t[i], t[j])
+	})
+	return t, true
+}
+
+// simplifyTerms applies one simplification to the conjunctions ts, and returns
+// the result and a boolean variable indicating whether ts was simplified.
+func simplifyTerms(ts [][]Expr) ([][]Expr, bool) {
+	// Absorption; i.e. x || y = x, where y implies x.
+	for i, x := range ts {
+		for j, y := range ts {
+			if i != j && termImplies(y, x) {
+				return append(ts[:j:j], ts[j+1:]...), true
+			}
+		}
+	}
+	// Negated absorption; i.e. (x && l) || (y && !l) = (x && l) || y, where y
+	// implies x.
+	for i, x := range ts {
+		for _, l := range x {
+			notl := NNF(NewNot(l))
+			for j, y := range ts {
+				if i != j && containsExpr(y, notl) && termImplies(without(y, notl), without(x, l)) {
+					us := append(ts[:0:0], ts...)
+					us[j] = without(y, notl)
+					return us, true
+				}
+			}
+		}
+	}
+	return ts, false
+}
+
+// fromTerms returns the disjunction of the given conjunctions.
+func fromTerms(ts [][]Expr) Expr {
+	var xs []Expr
+	for _, t := range ts {
+		xs = append(xs, NewAnd(t...))
+	}
+	return NewOr(xs...)
+}
+
+// termImplies reports whether the conjunction x implies each literal of the
+// conjunction y.
+func termImplies(x, y []Expr) bool {
+	for _, lit := range y {
+		if !termImpliesLit(x, lit) {
+			return false
+		}
+	}
+	return true
+}
+
+// termImpliesLit reports whether the conjunction x implies the literal lit.
+func termImpliesLit(x []Expr, lit Expr) bool {
+	for _, l := range x {
+		if implies(l, lit) {
+			return true
+		}
+	}
+	return false
+}
+
+// without returns the conjunction t without the literal lit.
+func without(t []Expr, lit Expr) []Expr {
+	var u []Expr
+	for _, l := range t {
+		if l.String() != lit.String() {
+			u = append(u, l)
+		}
+	}
+	return u
+}
+
+// implies reports whether the literal x implies the literal y; i.e. x and y are
+// equal, or x is the comparison of a symbol with a value and y the negated
+// comparison of the same symbol with another value.
+func implies(x, y Expr) bool {
+	if x.String() == y.String() {
+		return true
+	}
+	eq, ok := x.(Eq)
+	if !ok {
+		return false
+	}
+	not, ok := y.(*Not)
+	if !ok {
+		return false
+	}
+	other, ok := not.X.(Eq)
+	return ok && eq.X == other.X && eq.Value != other.Value
+}
+
+// litLess reports whether the literal x sorts before the literal y; by symbol,
+// compared value, and positive before negated literals.
+func litLess(x, y Expr) bool {
+	key := func(lit Expr) (Sym, string, bool) {
+		neg := false
+		if not, ok := lit.(*Not); ok {
+			lit, neg = not.X, true
+		}
+		if eq, ok := lit.(Eq); ok {
+			return eq.X, eq.Value, neg
+		}
+		return Sym(lit.String()), "", neg
+	}
+	xsym, xval, xneg := key(x)
+	ysym, yval, yneg := key(y)
+	if xsym != ysym {
+		return xsym < ysym
+	}
+	if xval != yval {
+		return xval < yval
+	}
+	return !xneg && yneg
+}
+
+// Common returns the literals common to each conjunction of the disjunctive
+// normal form of x; i.e. the literals of its first conjunction implied by each
+// conjunction.
+func Common(x Expr) []Expr {
+	ts := terms(x)
+	if len(ts) == 0 {
+		return nil
+	}
+	var lits []Expr
+	for _, lit := range ts[0] {
+		if Entails(x, lit) {
+			lits = append(lits, lit)
+		}
+	}
+	return lits
+}
+
+// Entails reports whether each conjunction of the disjunctive normal form of x
+// implies the literal lit; or false if x is false.
+func Entails(x, lit Expr) bool {
+	ts := terms(x)
+	if len(ts) == 0 {
+		return false
+	}
+	for _, t := range ts {
+		if !termImpliesLit(t, lit) {
+			return false
+		}
+	}
+	return true
+}
+
+// Without returns the disjunctive normal form of x, with the literal lit
+// removed from each conjunction.
+func Without(x, lit Expr) Expr {
+	var ts [][]Expr
+	for _, t := range terms(x) {
+		ts = append(ts, without(t, lit))
+	}
+	return DNF(fromTerms(ts))
+}
+
+// dnf returns the conjunctions of the disjunctive normal form of x, which must
+// be in negation normal form.
+func dnf(x Expr) [][]Expr {
+	switch x := x.(type) {
+	case Const:
+		if x {
+			return [][]Expr{nil}
+		}
+		return nil
+	case Sym, Eq, *Not:
+		return [][]Expr{{x}}
+	case *Or:
+		var terms [][]Expr
+		for _, y := range x.Xs {
+			terms = append(terms, dnf(y)...)
+		}
+		return terms
+	case *And:
+		terms := [][]Expr{nil}
+		for _, y := range x.Xs {
+			var product [][]Expr
+			for _, term := range terms {
+				for _, yterm := range dnf(y) {
+					product = append(product, append(term[:len(term):len(term)], yterm...))
+				}
+			}
+			terms = product
+		}
+		return terms
+	}
+	panic(fmt.Errorf("support for boolean expression %T not yet implemented", x))
+}
+
+// ### [ Simplification ] ######################################################
+
+// Simplify returns a simplified expression equivalent to x, in negation normal
+// form.
+//
+// The following simplifications are applied bottom-up, until no more apply.
+//
+//    constant folding      x && true = x, x && false = false
+//    idempotence           x && x = x
+//    complementation       x && !x = false, x || !x = true
+//    absorption            x && (x || y) = x, x || (x && y) = x
+//    negated absorption    x && (!x || y) = x && y, x || (!x && y) = x || y
+//    factoring             (x && y) || (x && z) = x && (y || z)
+//
+// The dual simplifications are applied to disjunctions.
+func Simplify(x Expr) Expr {
+	x = NNF(x)
+	for {
+		y := simplify(x)
+		if y.String() == x.String() {
+			return y
+		}
+		x = y
+	}
+}
+
+// simplify applies one round of simplifications to x, which must be in
+// negation normal form.
+func simplify(x Expr) Expr {
+	switch x := x.(type) {
+	case *And:
+		var ys []Expr
+		for _, y := range x.Xs {
+			ys = append(ys, simplify(y))
+		}
+		return simplifyOps(ys, true)
+	case *Or:
+		var ys []Expr
+		for _, y := range x.Xs {
+			ys = append(ys, simplify(y))
+		}
+		return simplifyOps(ys, false)
+	}
+	return x
+}
+
+// simplifyOps returns the simplified conjunction (if and is true) or
+// disjunction (otherwise) of the operands xs.
+func simplifyOps(xs []Expr, and bool) Expr {
+	// op returns the conjunction or disjunction of the given operands.
+	op := func(xs ...Expr) Expr {
+		if and {
+			return NewAnd(xs...)
+		}
+		return NewOr(xs...)
+	}
+	// dual returns the operands of x if it is a disjunction (in a conjunction)
+	// or a conjunction (in a disjunction).
+	dual := func(x Expr) ([]Expr, bool) {
+		if and {
+			if y, ok := x.(*Or); ok {
+				return y.Xs, true
+			}
+		} else {
+			if y, ok := x.(*And); ok {
+				return y.Xs, true
+			}
+		}
+		return nil, false
+	}
+	// Flatten nested operands and fold constants.
+	x := op(xs...)
+	switch y := x.(type) {
+	case *And:
+		xs = y.Xs
+	case *Or:
+		xs = y.Xs
+	default:
+		return x
+	}
+	// Idempotence.
+	var ys []Expr
+	for _, x := range xs {
+		if !containsExpr(ys, x) {
+			ys = append(ys, x)
+		}
+	}
+	// Complementation.
+	for _, y := range ys {
+		if containsExpr(ys, NNF(NewNot(y))) {
+			if and {
+				return False
+			}
+			return True
+		}
+	}
+	// Negated absorption.
+	for i, y := range ys {
+		yops, ok := dual(y)
+		if !ok {
+			continue
+		}
+		var rest []Expr
+		for _, yop := range yops {
+			if !containsExpr(ys, NNF(NewNot(yop))) {
+				rest = append(rest, yop)
+			}
+		}
+		if len(rest) < len(yops) {
+			if and {
+				ys[i] = NewOr(rest...)
+			} else {
+				ys[i] = NewAnd(rest...)
+			}
+		}
+	}
+	// Absorption.
+	var zs []Expr
+	for _, y := range ys {
+		absorbed := false
+		if yops, ok := dual(y); ok {
+			for _, yop := range yops {
+				if containsExpr(ys, yop) {
+					absorbed = true
+					break
+				}
+			}
+		}
+		if !absorbed {
+			zs = append(zs, y)
+		}
+	}
+	// Factoring.
+	if common := commonOps(zs, dual); len(common) > 0 {
+		var rests []Expr
+		for _, z := range zs {
+			var rest []Expr
+			zops, ok := dual(z)
+			if !ok {
+				zops = []Expr{z}
+			}
+			for _, zop := range zops {
+				if !containsExpr(common, zop) {
+					rest = append(rest, zop)
+				}
+			}
+			// The dual of the empty list of operands.
+			if and {
+				rests = append(rests, NewOr(rest...))
+			} else {
+				rests = append(rests, NewAnd(rest...))
+			}
+		}
+		if and {
+			return NewOr(append(common, NewAnd(rests...))...)
+		}
+		return NewAnd(append(common, NewOr(rests...))...)
+	}
+	return op(zs...)
+}
+
+// commonOps returns the operands common to each of the dual expressions xs,
+// where an expression which is not a dual expression is considered to be its
+// own single operand. commonOps returns nil if there are fewer than two
+// expressions.
+func commonOps(xs []Expr, dual func(x Expr) ([]Expr, bool)) []Expr {
+	if len(xs) < 2 {
+		return nil
+	}
+	ops := func(x Expr) []Expr {
+		if xops, ok := dual(x); ok {
+			return xops
+		}
+		return []Expr{x}
+	}
+	var common []Expr
+	for _, op := range ops(xs[0]) {
+		shared := true
+		for _, x := range xs[1:] {
+			if !containsExpr(ops(x), op) {
+				shared = false
+				break
+			}
+		}
+		if shared {
+			common = append(common, op)
+		}
+	}
+	return common
+}
+
+// containsExpr reports whether xs contains an expression structurally equal to
+// x.
+func containsExpr(xs []Expr, x Expr) bool {
+	s := x.String()
+	for _, y := range xs {
+		if y.String() == s {
+			return true
+		}
+	}
+	return false
+}
+
+// ### [ Evaluation ] ##########################################################
+
+// Syms returns the symbols of x, sorted by name.
+func Syms(x Expr) []Sym {
+	m := make(map[Sym]bool)
+	var walk func(x Expr)
+	walk = func(x Expr) {
+		switch x := x.(type) {
+		case Sym:
+			m[x] = true
+		case Eq:
+			m[x.X] = true
+		case *Not:
+			walk(x.X)
+		case *And:
+			for _, y := range x.Xs {
+				walk(y)
+			}
+		case *Or:
+			for _, y := range x.Xs {
+				walk(y)
+			}
+		}
+	}
+	walk(x)
+	var syms []Sym
+	for sym := range m {
+		syms = append(syms, sym)
+	}
+	sort.Slice(syms, func(i, j int) bool {
+		return syms[i] < syms[j]
+	})
+	return syms
+}
+
+// Eval evaluates x based on the given assignment of symbols. Comparisons are
+// assigned by their string representation (e.g. "x == 1"). Symbols not present
+// in env evaluate to false.
+func Eval(x Expr, env map[Sym]bool) bool {
+	switch x := x.(type) {
+	case Const:
+		return bool(x)
+	case Sym:
+		return env[x]
+	case Eq:
+		return env[Sym(x.String())]
+	case *Not:
+		return !Eval(x.X, env)
+	case *And:
+		for _, y := range x.Xs {
+			if !Eval(y, env) {
+				return false
+			}
+		}
+		return true
+	case *Or:
+		for _, y := range x.Xs {
+			if Eval(y, env) {
+				return true
+			}
+		}
+		return false
+	}
+	panic(fmt.Errorf("support for boolean expression %T not yet implemented", x))
+}
+
+// Equal reports whether x and y are equivalent, based on their truth tables.
+// Assignments in which a symbol compares equal to more than one value are
+// disregarded.
+func Equal(x, y Expr) bool {
+	// compared maps from the string representation of each comparison to the
+	// compared symbol.
+	compared := make(map[Sym]Sym)
+	var walk func(x Expr)
+	walk = func(x Expr) {
+		switch x := x.(type) {
+		case Sym:
+			compared[x] = ""
+		case Eq:
+			compared[Sym(x.String())] = x.X
+		case *Not:
+			walk(x.X)
+		case *And:
+			for _, y := range x.Xs {
+				walk(y)
+			}
+		case *Or:
+			for _, y := range x.Xs {
+				walk(y)
+			}
+		}
+	}
+	walk(x)
+	walk(y)
+	var atoms []Sym
+	for atom := range compared {
+		atoms = append(atoms, atom)
+	}
+	sort.Slice(atoms, func(i, j int) bool {
+		return atoms[i] < atoms[j]
+	})
+	if len(atoms) > 24 {
+		panic(fmt.Errorf("unable to compute truth table; too many symbols (%d)", len(atoms)))
+	}
+	env := make(map[Sym]bool)
+loop:
+	for i := 0; i < 1<<uint(len(atoms)); i++ {
+		equal := make(map[Sym]bool)
+		for j, atom := range atoms {
+			env[atom] = i&(1<<uint(j)) != 0
+			if sym := compared[atom]; env[atom] && len(sym) > 0 {
+				if equal[sym] {
+					continue loop
+				}
+				equal[sym] = true
+			}
+		}
+		if Eval(x, env) != Eval(y, env) {
+			return false
+		}
+	}
+	return true
+}
+
+// Substitute returns x with each symbol present in m replaced by its
+// corresponding expression.
+func Substitute(x Expr, m map[Sym]Expr) Expr {
+	switch x := x.(type) {
+	case Const, Eq:
+		return x
+	case Sym:
+		if y, ok := m[x]; ok {
+			return y
+		}
+		return x
+	case *Not:
+		return NewNot(Substitute(x.X, m))
+	case *And:
+		var ys []Expr
+		for _, y := range x.Xs {
+			ys = append(ys, Substitute(y, m))
+		}
+		return NewAnd(ys...)
+	case *Or:
+		var ys []Expr
+		for _, y := range x.Xs {
+			ys = append(ys, Substitute(y, m))
+		}
+		return NewOr(ys...)
+	}
+	panic(fmt.Errorf("support for boolean expression %T not yet implemented", x))
+}
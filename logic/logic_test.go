@@ -0,0 +1,116 @@
+package logic
+
+import "testing"
+
+var (
+	a = Sym("a")
+	b = Sym("b")
+	c = Sym("c")
+	d = Sym("d")
+	// x == 1, x == 2
+	x1 = Eq{X: "x", Value: "1"}
+	x2 = Eq{X: "x", Value: "2"}
+)
+
+func TestSimplify(t *testing.T) {
+	golden := []struct {
+		in   Expr
+		want string
+	}{
+		// Constant folding.
+		{in: NewAnd(a, True), want: "a"},
+		{in: NewOr(a, True), want: "true"},
+		{in: NewNot(NewNot(a)), want: "a"},
+		// Idempotence.
+		{in: NewAnd(a, b, a), want: "a && b"},
+		// Complementation.
+		{in: NewAnd(a, b, NewNot(a)), want: "false"},
+		{in: NewOr(NewNot(b), a, b), want: "true"},
+		// Absorption.
+		{in: NewAnd(a, NewOr(a, b)), want: "a"},
+		{in: NewOr(NewAnd(a, b), a), want: "a"},
+		// Factoring.
+		{in: NewOr(NewAnd(a, b), NewAnd(a, c)), want: "a && (b || c)"},
+		{in: NewAnd(NewOr(a, b), NewOr(a, c)), want: "a || b && c"},
+		{in: NewOr(NewAnd(a, b), NewAnd(a, NewNot(b))), want: "a"},
+		// Negated absorption.
+		{in: NewOr(a, NewAnd(NewNot(a), b)), want: "a || b"},
+		// De Morgan.
+		{in: NewNot(NewAnd(a, NewOr(b, NewNot(c)))), want: "!a || !b && c"},
+		// Nested.
+		{in: NewOr(NewAnd(a, b, c), NewAnd(a, b, d), NewAnd(a, NewNot(b))), want: "a && (c || d || !b)"},
+	}
+	for _, gold := range golden {
+		got := Simplify(gold.in)
+		if got.String() != gold.want {
+			t.Errorf("%v; output mismatch; expected `%s`, got `%v`", gold.in, gold.want, got)
+			continue
+		}
+		if !Equal(gold.in, got) {
+			t.Errorf("%v; simplified expression `%v` not equivalent", gold.in, got)
+		}
+	}
+}
+
+func TestDNF(t *testing.T) {
+	golden := []struct {
+		in   Expr
+		want string
+	}{
+		{in: NewAnd(NewOr(a, b), c), want: "a && c || b && c"},
+		{in: NewOr(NewAnd(a, b), NewAnd(b, a), NewAnd(a, NewNot(a)), a), want: "a"},
+		{in: NewAnd(NewOr(a, b), NewOr(c, d)), want: "a && c || a && d || b && c || b && d"},
+		{in: NewNot(NewOr(a, NewAnd(b, c))), want: "!a && !b || !a && !c"},
+		// Mutually exclusive comparisons.
+		{in: NewAnd(x1, x2), want: "false"},
+		{in: NewAnd(x1, NewNot(x2)), want: "x == 1"},
+		{in: NewOr(NewAnd(a, x1), NewAnd(a, NewNot(x1), NewNot(x2))), want: "a && x != 2"},
+	}
+	for _, gold := range golden {
+		got := DNF(gold.in)
+		if got.String() != gold.want {
+			t.Errorf("%v; output mismatch; expected `%s`, got `%v`", gold.in, gold.want, got)
+			continue
+		}
+		if !Equal(gold.in, got) {
+			t.Errorf("%v; normal form `%v` not equivalent", gold.in, got)
+		}
+	}
+}
+
+func TestEqual(t *testing.T) {
+	golden := []struct {
+		x, y Expr
+		want bool
+	}{
+		{x: NewNot(NewAnd(a, b)), y: NewOr(NewNot(a), NewNot(b)), want: true},
+		{x: NewOr(a, NewAnd(NewNot(a), b)), y: NewOr(a, b), want: true},
+		{x: NewAnd(a, b), y: NewOr(a, b), want: false},
+		{x: NewOr(a, NewNot(a)), y: True, want: true},
+		{x: NewAnd(x1, x2), y: False, want: true},
+		{x: NewOr(x1, NewNot(x2)), y: NewNot(x2), want: true},
+		{x: NewOr(x1, x2), y: True, want: false},
+	}
+	for _, gold := range golden {
+		if got := Equal(gold.x, gold.y); got != gold.want {
+			t.Errorf("equivalence mismatch of `%v` and `%v`; expected %v, got %v", gold.x, gold.y, gold.want, got)
+		}
+	}
+}
+
+func TestEntails(t *testing.T) {
+	golden := []struct {
+		x, lit Expr
+		want   bool
+	}{
+		{x: NewOr(NewAnd(a, b), NewAnd(a, c)), lit: a, want: true},
+		{x: NewOr(NewAnd(a, b), NewAnd(a, c)), lit: b, want: false},
+		{x: NewOr(NewAnd(a, x1), NewAnd(b, x2)), lit: NewNot(Eq{X: "x", Value: "3"}), want: true},
+		{x: False, lit: a, want: false},
+	}
+	for _, gold := range golden {
+		if got := Entails(gold.x, gold.lit); got != gold.want {
+			t.Errorf("entailment mismatch of `%v` by `%v`; expected %v, got %v", gold.lit, gold.x, gold.want, got)
+		}
+	}
+}